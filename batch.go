@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result of solving one line of a batch file.
+type BatchResult struct {
+	// 1-indexed line of the batch file.
+	Line int
+	Output JSONOutput
+	Err error
+}
+
+// A line of JSONL batch output. Output is left out when the line has an error.
+type JSONBatchResult struct {
+	Line int `json:"line"`
+	Error string `json:"error,omitempty"`
+	*JSONOutput
+}
+
+type batchJob struct {
+	// Order of the job among every job, which skips blank lines.
+	index int
+	line int
+	text string
+}
+
+type batchDone struct {
+	index int
+	result BatchResult
+}
+
+// Reads a line of a batch file, either board markup or a JSONInput such as
+// {"board": "RRB...", "settings": {"combo": 7}}.
+func ParseBatchLine(text string, defaults map[string]string) (SolveInput, error) {
+	input := JSONInput{Board: text}
+	if strings.HasPrefix(text, "{") {
+		decoded, err := ReadJSONInput(strings.NewReader(text))
+		if err != nil {
+			return SolveInput{}, err
+		}
+		input = decoded
+	}
+	return ParseSolveInput(input, defaults)
+}
+
+func solveBatchJob(ctx context.Context, job batchJob, defaults map[string]string) BatchResult {
+	input, err := ParseBatchLine(job.text, defaults)
+	if err != nil {
+		return BatchResult{Line: job.line, Err: err}
+	}
+	start_time := time.Now()
+	result := input.Settings.Solve(ctx, input.Board, nil)
+	output, err := input.Output(result, time.Since(start_time))
+	return BatchResult{job.line, output, err}
+}
+
+// Solves every board in the reader, one per line, on a pool of workers.
+// Defaults are used for settings a line doesn't give, see ParseSolveInput.
+// Write is called with each result in the order of the input, and blank lines
+// and lines starting with # are skipped.
+func SolveBatch(ctx context.Context, reader io.Reader, defaults map[string]string, workers int,
+                write func(BatchResult) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan batchJob)
+	read_err := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(reader)
+		line, index := 0, 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			select {
+			case jobs <- batchJob{index, line, text}:
+				index++
+			case <-ctx.Done():
+				read_err <- ctx.Err()
+				return
+			}
+		}
+		read_err <- scanner.Err()
+	}()
+
+	results := make(chan batchDone)
+	wait_group := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wait_group.Add(1)
+		go func() {
+			defer wait_group.Done()
+			for job := range jobs {
+				results <- batchDone{job.index, solveBatchJob(ctx, job, defaults)}
+			}
+		}()
+	}
+	go func() {
+		wait_group.Wait()
+		close(results)
+	}()
+
+	// Results finish out of order, so hold each until every earlier one is
+	// written.
+	var write_err error
+	pending := map[int]BatchResult{}
+	next := 0
+	for done := range results {
+		pending[done.index] = done.result
+		for result, exists := pending[next]; exists; result, exists = pending[next] {
+			if write_err == nil {
+				if write_err = write(result); write_err != nil {
+					cancel()
+				}
+			}
+			delete(pending, next)
+			next++
+		}
+	}
+	if write_err != nil {
+		return write_err
+	}
+	return <-read_err
+}
+
+// Writes each result as a line of JSON.
+func MakeJSONLBatchWriter(writer io.Writer) func(BatchResult) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	return func(result BatchResult) error {
+		line := JSONBatchResult{Line: result.Line}
+		if result.Err != nil {
+			line.Error = result.Err.Error()
+		} else {
+			line.JSONOutput = &result.Output
+		}
+		return encoder.Encode(line)
+	}
+}
+
+var BATCH_CSV_HEADER []string = []string{
+	"line", "board", "combos", "starting_position", "directions", "moves", "score", "reason", "elapsed_ms", "error",
+}
+
+// Writes each result as a row of CSV, after a header row. Starting positions
+// are written as "y,x" and directions are separated by spaces.
+func MakeCSVBatchWriter(writer io.Writer) func(BatchResult) error {
+	csv_writer := csv.NewWriter(writer)
+	wrote_header := false
+	return func(result BatchResult) error {
+		if !wrote_header {
+			csv_writer.Write(BATCH_CSV_HEADER)
+			wrote_header = true
+		}
+		row := make([]string, len(BATCH_CSV_HEADER))
+		row[0] = strconv.Itoa(result.Line)
+		if result.Err != nil {
+			row[9] = result.Err.Error()
+		} else {
+			output := result.Output
+			row[1] = output.Board
+			row[2] = strconv.Itoa(len(output.Combos))
+			row[3] = fmt.Sprintf("%d,%d", output.StartingPosition[0], output.StartingPosition[1])
+			row[4] = strings.Join(output.Directions, " ")
+			row[5] = strconv.Itoa(len(output.Directions))
+			row[6] = strconv.Itoa(output.Stats.Score)
+			row[7] = output.Stats.Reason
+			row[8] = strconv.FormatInt(output.Stats.ElapsedMs, 10)
+		}
+		csv_writer.Write(row)
+		// Flush each row so results show up as they finish.
+		csv_writer.Flush()
+		return csv_writer.Error()
+	}
+}
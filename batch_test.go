@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testBatch = `RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG
+
+# Skipped
+{"board": "RRBBHHDDLLGGRRBBHHDDLLGGRRBBHH", "settings": {"combo": 3}}
+{"board": "RRBBHHDDLLGGRRBBHHDD", "settings": {"width": 5}}
+not a board
+{"board": "RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", "settings": {"diagonals": true}}
+`
+
+func TestSolveBatch_WritesInOrder(t *testing.T) {
+	results := []BatchResult{}
+	write := func(result BatchResult) error {
+		results = append(results, result)
+		return nil
+	}
+	defaults := map[string]string{"combo": "2", "timeout_ms": "2000", "format": "svg"}
+	err := SolveBatch(context.Background(), strings.NewReader(testBatch), defaults, 3, write)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []int{1, 4, 5, 6, 7}
+	if len(results) != len(lines) {
+		t.Fatalf("Expected %d results, got %d", len(lines), len(results))
+	}
+	for i, result := range results {
+		if result.Line != lines[i] {
+			t.Errorf("Expected line %d, got %d", lines[i], result.Line)
+		}
+		if (result.Err != nil) != (result.Line == 6) {
+			t.Errorf("Line %d: unexpected error %v", result.Line, result.Err)
+		}
+	}
+	if results[1].Output.Settings["combo"] != "3" || results[0].Output.Settings["combo"] != "2" {
+		t.Error("Expected settings on a line to override the defaults")
+	}
+	if results[2].Output.Width != 5 || results[4].Output.Settings["diagonals"] != "true" {
+		t.Error("Expected per line settings to be used")
+	}
+}
+
+func TestSolveBatch_StopsOnWriteError(t *testing.T) {
+	write := func(result BatchResult) error {
+		return fmt.Errorf("Disk full")
+	}
+	defaults := map[string]string{"combo": "2"}
+	err := SolveBatch(context.Background(), strings.NewReader(testBatch), defaults, 2, write)
+	if err == nil || err.Error() != "Disk full" {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+}
+
+func TestBatchWriters(t *testing.T) {
+	output := JSONOutput{
+		Board: "RRR",
+		StartingPosition: [2]int{1, 2},
+		Directions: []string{"U", "L"},
+		Combos: []JSONCombo{JSONCombo{}},
+		Stats: JSONStats{Score: 40, Reason: "Goal met", ElapsedMs: 12},
+	}
+	results := []BatchResult{{Line: 1, Output: output}, {Line: 3, Err: fmt.Errorf("Bad board")}}
+
+	csv_buffer := bytes.Buffer{}
+	jsonl_buffer := bytes.Buffer{}
+	write_csv := MakeCSVBatchWriter(&csv_buffer)
+	write_jsonl := MakeJSONLBatchWriter(&jsonl_buffer)
+	for _, result := range results {
+		if err := write_csv(result); err != nil {
+			t.Fatal(err)
+		}
+		if err := write_jsonl(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected_csv := strings.Join(BATCH_CSV_HEADER, ",") + "\n" +
+		"1,RRR,1,\"1,2\",U L,2,40,Goal met,12,\n" +
+		"3,,,,,,,,,Bad board\n"
+	if csv_buffer.String() != expected_csv {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected_csv, csv_buffer.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(jsonl_buffer.String()), "\n")
+	if len(lines) != 2 || lines[1] != `{"line":3,"error":"Bad board"}` {
+		t.Errorf("Unexpected JSON lines:\n%s", jsonl_buffer.String())
+	}
+	decoded := JSONOutput{}
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil || decoded.Board != "RRR" {
+		t.Errorf("Expected the output on the first line, got %s", lines[0])
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"sort"
+)
+
+// Keeps the best states, at most width of them, in a stable order so ties are
+// always broken the same way.
+func keepBestStates(states []AStarState, width int) []AStarState {
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].score > states[j].score
+	})
+	if len(states) > width {
+		states = states[:width]
+	}
+	return states
+}
+
+// Adds the state to the level, replacing a state at the same position with a
+// lower score.
+func addBeamState(level []AStarState, positions map[uint64]int, state AStarState, requirements SolveRequirement) []AStarState {
+	key := state.TimedHash(requirements.MoveTimer, requirements.PriorDirections)
+	if index, exists := positions[key]; exists {
+		if state.score > level[index].score {
+			level[index] = state
+		}
+		return level
+	}
+	positions[key] = len(level)
+	return append(level, state)
+}
+
+// Searches one path length at a time, keeping only the best width states of
+// each length, until the requirements are met, RejectionFn rejects every
+// child or the context is done. Duplicate positions are only removed within a
+// length, so at most width times the number of directions boards are held at
+// once and memory doesn't grow with the length of the solve.
+//
+// RejectionFn should only limit paths, e.g. by length, since a rejection
+// function which remembers every board grows with the solve.
+func BeamSolve(ctx context.Context, board Board, requirements SolveRequirement, width int) SolveResult {
+	if width < 1 {
+		width = 1
+	}
+	moves := []Direction{RIGHT, DOWN, LEFT, UP}
+	if requirements.AllowDiagonals {
+		moves = []Direction{RIGHT, DOWN_RIGHT, DOWN, DOWN_LEFT, LEFT, UP_LEFT, UP, UP_RIGHT}
+	}
+	var starting_positions []Pair = requirements.StartingPositions
+	if len(starting_positions) == 0 {
+		for y := uint8(0); y < board.Height; y++ {
+			for x := uint8(0); x < board.Width; x++ {
+				starting_positions = append(starting_positions, Pair{y, x})
+			}
+		}
+	}
+
+	checked := 0
+	skipped := 0
+	// Keep the hash of every state up to date as it is swapped.
+	board.Rehash()
+	level := make([]AStarState, 0)
+	positions := map[uint64]int{}
+	for _, starting_pos := range starting_positions {
+		for _, move := range moves {
+			board, err := board.Swap(starting_pos, move)
+			if err != nil {
+				continue
+			}
+			if requirements.MoveTimer.Enabled() {
+				start, end := requirements.stepSeconds([]Direction{move})
+				board.TickSpinners(start, end, starting_pos.Swap(move))
+			}
+			new_state := AStarState{
+				board,
+				starting_pos,
+				starting_pos.Swap(move),
+				[]Direction{move},
+				0,
+			}
+			new_state.score = requirements.ScoreState(new_state)
+			if requirements.RejectionFn(new_state) {
+				skipped++
+				continue
+			}
+			level = addBeamState(level, positions, new_state, requirements)
+		}
+	}
+	level = keepBestStates(level, width)
+
+	best_state := AStarState{score: -100000}
+	reason := GOAL_MET
+	for !requirements.FinishedFn(best_state) {
+		if len(level) == 0 {
+			reason = EXHAUSTED
+			break
+		}
+		if stop_reason, stopped := contextStopReason(ctx); stopped {
+			reason = stop_reason
+			break
+		}
+
+		for _, current_state := range level {
+			checked++
+			if current_state.score > best_state.score &&
+			   (!requirements.Restriction.Enabled() ||
+			    !requirements.Restriction.IsViolated(current_state.board.GetAllCombos())) {
+				best_state = current_state
+				if requirements.OnImproved != nil {
+					requirements.OnImproved(Moves{best_state.starting_pos, best_state.moves}, best_state.score)
+				}
+				if requirements.FinishedFn(best_state) {
+					break
+				}
+			}
+		}
+		if requirements.FinishedFn(best_state) {
+			break
+		}
+
+		next_level := make([]AStarState, 0, len(level) * len(moves))
+		positions = map[uint64]int{}
+		for _, current_state := range level {
+			for _, next_state := range current_state.NextStates(requirements) {
+				next_state.score = requirements.ScoreState(next_state)
+				if requirements.RejectionFn(next_state) {
+					skipped++
+					continue
+				}
+				next_level = addBeamState(next_level, positions, next_state, requirements)
+			}
+		}
+		level = keepBestStates(next_level, width)
+	}
+
+	result_board := best_state.board
+	if best_state.moves == nil {
+		result_board = board
+	}
+	return SolveResult{
+		Moves{best_state.starting_pos, best_state.moves},
+		result_board,
+		SolveStats{checked, skipped, best_state.score},
+		reason,
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func makeBeamRequirement(combos int, max_moves int) SolveRequirement {
+	settings := MakeSolveSettings()
+	settings.ComboMinimum = combos
+	settings.MaxMoves = max_moves
+	requirement := settings.Requirement(swng_board)
+	requirement.RejectionFn = MakeLimitRejectionFunction(settings)
+	return requirement
+}
+
+func TestBeamSolve_GoalMet(t *testing.T) {
+	result := BeamSolve(context.Background(), swng_board, makeBeamRequirement(8, 50), 200)
+	if result.Reason != GOAL_MET {
+		t.Fatalf("Expected %s, got %s", GOAL_MET, result.Reason)
+	}
+	moved, err := swng_board.ApplyMoves(result.Moves, MoveTimer{})
+	if err != nil || moved.SimpleString() != result.Board.SimpleString() {
+		t.Errorf("Expected the board after %s", result.Moves)
+	}
+	if combos := len(result.Board.GetAllCombos()); combos < 8 {
+		t.Errorf("Expected at least 8 combos, got %d", combos)
+	}
+}
+
+func TestBeamSolve_BoundedByWidth(t *testing.T) {
+	width, max_moves := 10, 5
+	result := BeamSolve(context.Background(), swng_board, makeBeamRequirement(20, max_moves), width)
+	if result.Reason != EXHAUSTED {
+		t.Errorf("Expected %s, got %s", EXHAUSTED, result.Reason)
+	}
+	if result.Stats.Checked > width * max_moves {
+		t.Errorf("Expected at most %d states of each length, checked %d", width, result.Stats.Checked)
+	}
+	if len(result.Moves.Directions) == 0 || len(result.Moves.Directions) > max_moves {
+		t.Errorf("Expected a path of at most %d moves, got %s", max_moves, result.Moves)
+	}
+}
+
+func TestBeamSolve_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := BeamSolve(ctx, swng_board, makeBeamRequirement(20, 50), 10)
+	if result.Reason != CANCELLED || result.Stats.Checked != 0 {
+		t.Errorf("Expected the solve to be cancelled immediately, checked %d: %s",
+			result.Stats.Checked, result.Reason)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"math/bits"
+)
+
+// Bitboards keep one mask per attribute, with bit y * BITBOARD_STRIDE + x set
+// for each orb of that attribute. Rows are padded to BITBOARD_STRIDE so
+// shifting a mask along a row never carries an orb into the next row.
+const BITBOARD_STRIDE = 8
+
+// Orbs of a board as masks, used by GetAllMatches to find combos and cascade
+// with shifts instead of walking every slot.
+type Bitboard struct {
+	// Orbs of each attribute. EMPTY is always zero.
+	Attributes [UNKNOWN + 1]uint64
+	// Enhanced orbs of any attribute.
+	Enhanced uint64
+	Height uint8
+	Width uint8
+	// Board the masks were made from, for its minimum matches and combo shapes.
+	board Board
+}
+
+// Bitboard of the board, or false if the board is wider than 7, taller than 8
+// or has unmatchable orbs, which markCombos only skips when they would start a
+// match.
+func MakeBitboard(board Board) (Bitboard, bool) {
+	bitboard := Bitboard{Height: board.Height, Width: board.Width, board: board}
+	if board.Width >= BITBOARD_STRIDE || int(board.Height) * BITBOARD_STRIDE > 64 ||
+	   len(board.Slots) != int(board.Height) * int(board.Width) {
+		return bitboard, false
+	}
+	for i, slot := range board.Slots {
+		if slot.Orb.Attribute > UNKNOWN || slot.Orb.State & UNMATCHABLE != 0 {
+			return bitboard, false
+		}
+		if slot.Orb.Attribute == EMPTY {
+			continue
+		}
+		bit := bitboard.bit(Pair{uint8(i / int(board.Width)), uint8(i % int(board.Width))})
+		bitboard.Attributes[slot.Orb.Attribute] |= bit
+		if slot.Orb.State & ENHANCED != 0 {
+			bitboard.Enhanced |= bit
+		}
+	}
+	return bitboard, true
+}
+
+func (self Bitboard) bit(placement Pair) uint64 {
+	return 1 << (uint(placement.Y) * BITBOARD_STRIDE + uint(placement.X))
+}
+
+func (self Bitboard) placement(index int) Pair {
+	return Pair{uint8(index / BITBOARD_STRIDE), uint8(index % BITBOARD_STRIDE)}
+}
+
+// Every space of the row.
+func (self Bitboard) rowMask(y uint8) uint64 {
+	return (1 << self.Width - 1) << (uint(y) * BITBOARD_STRIDE)
+}
+
+// Every space of the column.
+func (self Bitboard) columnMask(x uint8) uint64 {
+	mask := uint64(0)
+	for y := uint8(0); y < self.Height; y++ {
+		mask |= self.bit(Pair{y, x})
+	}
+	return mask
+}
+
+// Spaces with an orb in them.
+func (self Bitboard) Occupied() uint64 {
+	occupied := uint64(0)
+	for _, mask := range self.Attributes {
+		occupied |= mask
+	}
+	return occupied
+}
+
+// Orbs in three or more in a row of the same attribute, the same orbs
+// markCombos marks.
+func (self Bitboard) Matches() uint64 {
+	marked := uint64(0)
+	for attribute := FIRE; attribute < UNKNOWN; attribute++ {
+		mask := self.Attributes[attribute]
+		// The first orb of each run of three.
+		horizontal := mask & (mask >> 1) & (mask >> 2)
+		vertical := mask & (mask >> BITBOARD_STRIDE) & (mask >> (2 * BITBOARD_STRIDE))
+		marked |= horizontal | horizontal << 1 | horizontal << 2 |
+			vertical | vertical << BITBOARD_STRIDE | vertical << (2 * BITBOARD_STRIDE)
+	}
+	return marked
+}
+
+// Removes the orbs in the mask.
+func (self *Bitboard) Clear(mask uint64) {
+	for attribute := range self.Attributes {
+		self.Attributes[attribute] &^= mask
+	}
+	self.Enhanced &^= mask
+}
+
+// Drops every orb onto the orbs below it, the same as dropOrbs.
+func (self *Bitboard) Drop() {
+	// Every space which has a space below it.
+	above_bottom := uint64(0)
+	for y := uint8(0); y + 1 < self.Height; y++ {
+		above_bottom |= self.rowMask(y)
+	}
+	for {
+		occupied := self.Occupied()
+		falling := occupied &^ (occupied >> BITBOARD_STRIDE) & above_bottom
+		if falling == 0 {
+			return
+		}
+		for attribute, mask := range self.Attributes {
+			self.Attributes[attribute] = mask &^ falling | (mask & falling) << BITBOARD_STRIDE
+		}
+		self.Enhanced = self.Enhanced &^ falling | (self.Enhanced & falling) << BITBOARD_STRIDE
+	}
+}
+
+// Blows up each bomb which isn't matched, clearing its row and column other
+// than bombs, the same as GetMatches. Returns false if there were none.
+func (self *Bitboard) explodeBombs(marked uint64) bool {
+	unmatched := self.Attributes[BOMB] &^ marked
+	if unmatched == 0 {
+		return false
+	}
+	cleared := uint64(0)
+	for remaining := unmatched; remaining != 0; remaining &= remaining - 1 {
+		placement := self.placement(bits.TrailingZeros64(remaining))
+		cleared |= self.rowMask(placement.Y) | self.columnMask(placement.X)
+	}
+	self.Clear(cleared &^ self.Attributes[BOMB] | unmatched)
+	return true
+}
+
+// Connected orbs of one attribute.
+type bitboardGroup struct {
+	attribute OrbAttribute
+	mask uint64
+}
+
+// Grows the orbs in group to every orb in same connected to them, shifting the
+// group one space in each direction at a time. The row padding keeps shifts
+// along a row from reaching the next row.
+func floodFill(group uint64, same uint64) uint64 {
+	for {
+		grown := (group | group << 1 | group >> 1 |
+			group << BITBOARD_STRIDE | group >> BITBOARD_STRIDE) & same
+		if grown == group {
+			return group
+		}
+		group = grown
+	}
+}
+
+// Placements of the orbs in the group in the order GetMatches finds them,
+// searching right, left, down then up from the first orb.
+func (self Bitboard) groupPlacements(group uint64) []Pair {
+	first := bits.TrailingZeros64(group)
+	placements := make([]Pair, 1, bits.OnesCount64(group))
+	placements[0] = self.placement(first)
+	unvisited := group &^ (1 << uint(first))
+	for j := 0; j < len(placements); j++ {
+		index := int(placements[j].Y) * BITBOARD_STRIDE + int(placements[j].X)
+		neighbors := [4]int{index + 1, index - 1, index + BITBOARD_STRIDE, index - BITBOARD_STRIDE}
+		for _, neighbor := range neighbors {
+			if neighbor < 0 || unvisited & (1 << uint(neighbor)) == 0 {
+				continue
+			}
+			unvisited &^= 1 << uint(neighbor)
+			placements = append(placements, self.placement(neighbor))
+		}
+	}
+	return placements
+}
+
+func (self Bitboard) groupCombo(group bitboardGroup) BoardCombo {
+	placements := self.groupPlacements(group.mask)
+	enhanced_count := bits.OnesCount64(group.mask & self.Enhanced)
+	return BoardCombo{group.attribute, placements, self.board.getComboShapes(placements), enhanced_count}
+}
+
+// Groups the marked orbs into connected orbs of the same attribute, in the
+// same order as GetMatches. Groups big enough to combo are appended to combos
+// and their orbs returned as cleared. Groups too small are only kept as masks,
+// since GetAllMatches only needs their placements once nothing combos.
+func (self Bitboard) groupMatches(marked uint64, combos []BoardCombo) ([]BoardCombo, []bitboardGroup, uint64) {
+	var failed []bitboardGroup
+	cleared := uint64(0)
+	for remaining := marked; remaining != 0; {
+		start := remaining & -remaining
+		attribute := EMPTY
+		for candidate := FIRE; candidate <= UNKNOWN; candidate++ {
+			if self.Attributes[candidate] & start != 0 {
+				attribute = candidate
+				break
+			}
+		}
+		group := bitboardGroup{attribute, floodFill(start, self.Attributes[attribute] & remaining)}
+		remaining &^= group.mask
+		if bits.OnesCount64(group.mask) < self.board.MinimumMatchFor(attribute) {
+			failed = append(failed, group)
+			continue
+		}
+		combos = append(combos, self.groupCombo(group))
+		cleared |= group.mask
+	}
+	return combos, failed, cleared
+}
+
+// Same as Board.GetAllMatches.
+func (self Bitboard) GetAllMatches() ([]BoardCombo, []BoardCombo) {
+	// Every combo clears at least 3 orbs and nothing falls in, so this is the
+	// most combos there can be.
+	all_combos := make([]BoardCombo, 0, bits.OnesCount64(self.Occupied()) / 3)
+	for {
+		marked := self.Matches()
+		for self.explodeBombs(marked) {
+			marked = self.Matches()
+		}
+		combos, failed, cleared := self.groupMatches(marked, all_combos)
+		if len(combos) == len(all_combos) {
+			failed_combos := make([]BoardCombo, 0, len(failed))
+			for _, group := range failed {
+				failed_combos = append(failed_combos, self.groupCombo(group))
+			}
+			return all_combos, failed_combos
+		}
+		all_combos = combos
+		self.Clear(cleared)
+		self.Drop()
+	}
+}
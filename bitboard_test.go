@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Random board which is mostly a few attributes, so combos and cascades are
+// common, with some bombs, unknown orbs, enhanced orbs and empty spaces.
+func createDifferentialBoard(random *rand.Rand, width uint8) Board {
+	board := CreateEmptyBoard(width)
+	common := []OrbAttribute{FIRE, WATER, WOOD}
+	rare := []OrbAttribute{EMPTY, LIGHT, DARK, HEART, JAMMER, POISON, MORTAL_POISON, BOMB, UNKNOWN}
+	for i := range board.Slots {
+		orb := &board.Slots[i].Orb
+		if random.Intn(6) == 0 {
+			orb.Attribute = rare[random.Intn(len(rare))]
+		} else {
+			orb.Attribute = common[random.Intn(len(common))]
+		}
+		if random.Intn(5) == 0 {
+			orb.State |= ENHANCED
+		}
+	}
+	if random.Intn(3) == 0 {
+		board.MinimumMatch = 4
+		board.MinimumMatches = map[OrbAttribute]int{WATER: 5, JAMMER: NEVER_MATCH}
+	}
+	return board
+}
+
+func TestBitboard_MatchesGetAllMatches(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		board := createDifferentialBoard(random, uint8(5 + i % 3))
+		bitboard, ok := MakeBitboard(board)
+		if !ok {
+			t.Fatalf("Expected a bitboard for:\n%s", board)
+		}
+		combos, failed := bitboard.GetAllMatches()
+		expected_combos, expected_failed := board.getAllMatchesBySlots()
+		if !reflect.DeepEqual(combos, expected_combos) || !reflect.DeepEqual(failed, expected_failed) {
+			t.Fatalf("Expected %v and %v, got %v and %v for:\n%s",
+				expected_combos, expected_failed, combos, failed, board)
+		}
+	}
+}
+
+func TestBitboard_MatchesGetAllCombosOnRandomBoards(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		board := CreateRandomBoard(uint8(5 + i % 3))
+		expected, _ := board.getAllMatchesBySlots()
+		if combos := board.GetAllCombos(); !reflect.DeepEqual(combos, expected) {
+			t.Fatalf("Expected %v, got %v for:\n%s", expected, combos, board)
+		}
+	}
+}
+
+func TestMakeBitboard_SkipsUnmatchableOrbs(t *testing.T) {
+	board, err := ParseBoard("RRR~RRRRRRRRRRRRRRRRRRRRRRRRRRR", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := MakeBitboard(board); ok {
+		t.Error("Expected boards with unmatchable orbs to use the slots")
+	}
+	if len(board.GetAllCombos()) == 0 {
+		t.Error("Expected the board to still combo")
+	}
+}
+
+func BenchmarkGetAllCombos_Bitboard(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		swng_board.GetAllCombos()
+	}
+}
+
+func BenchmarkGetAllCombos_Slots(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		swng_board.getAllMatchesBySlots()
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"math/rand" // Runs deterministically unless we set a seed.
 )
@@ -12,6 +13,11 @@ type Pair struct {
 	X uint8
 }
 
+// Positions and dimensions are a uint8, see ToPos, and loops over positions
+// such as GetMatches stop below uint8(len(Slots)), so no board has more spaces
+// or a larger dimension than this.
+const MAX_BOARD_SPACES = 255
+
 func (self Pair) ToPos(board Board) uint8 {
 	return self.Y * board.Width + self.X
 }
@@ -80,6 +86,12 @@ func (self BoardSpace) String() string {
 	if self.State & TAPE != 0 {
 		return fmt.Sprintf("<%s>", string(self.Orb.String()[1]))
 	}
+	if self.State & SPINNER_1S != 0 {
+		return fmt.Sprintf("[%s]", string(self.Orb.String()[1]))
+	}
+	if self.State & SPINNER_2S != 0 {
+		return fmt.Sprintf("(%s)", string(self.Orb.String()[1]))
+	}
 	return self.Orb.String()
 }
 
@@ -87,7 +99,15 @@ type Board struct {
 	Slots []BoardSpace
 	Height uint8
 	Width uint8
+	// Default number of connected orbs needed to combo.
 	MinimumMatch int
+	// Per attribute overrides of MinimumMatch, e.g. {HEART: 5} for Hearts
+	// needing 5 orbs. Shared between clones, so treat it as read-only.
+	MinimumMatches map[OrbAttribute]int
+	// Zobrist hash of the orbs, kept up to date by Swap and TickSpinners once
+	// hashed is set by Rehash. See Hash.
+	hash uint64
+	hashed bool
 }
 
 func (self Board) Clone() Board {
@@ -95,20 +115,94 @@ func (self Board) Clone() Board {
 	for i, board_space := range self.Slots {
 		new_slots[i] = board_space.Clone()
 	}
-	return Board{new_slots, self.Height, self.Width, self.MinimumMatch}
+	return Board{new_slots, self.Height, self.Width, self.MinimumMatch, self.MinimumMatches, 0, false}
+}
+
+// Minimum match for attributes which can't be matched at all, e.g. Jammers in
+// some dungeons.
+const NEVER_MATCH int = 1 << 16
+
+// Parses a minimum match spec of comma separated values. A bare number sets
+// the default, letter=number overrides a single attribute and letter=none
+// stops that attribute from matching. e.g. "3,H=5,J=none". Matches need at
+// least 3 orbs, so smaller numbers are rejected.
+func ParseMinimumMatches(s string) (int, map[OrbAttribute]int, error) {
+	minimum := 3
+	overrides := map[OrbAttribute]int{}
+	if s == "" {
+		return minimum, overrides, nil
+	}
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		letter_value := strings.SplitN(spec, "=", 2)
+		if len(letter_value) == 1 {
+			value, err := strconv.Atoi(spec)
+			if err != nil || value < 3 {
+				return minimum, overrides, fmt.Errorf("Invalid minimum match \"%s\"", spec)
+			}
+			minimum = value
+			continue
+		}
+		attribute, exists := LetterToAttribute[letter_value[0]]
+		if !exists || attribute == EMPTY {
+			return minimum, overrides, fmt.Errorf("Unknown attribute \"%s\"", letter_value[0])
+		}
+		if letter_value[1] == "none" {
+			overrides[attribute] = NEVER_MATCH
+			continue
+		}
+		value, err := strconv.Atoi(letter_value[1])
+		if err != nil || value < 3 {
+			return minimum, overrides, fmt.Errorf("Invalid minimum match \"%s\"", spec)
+		}
+		overrides[attribute] = value
+	}
+	return minimum, overrides, nil
 }
 
+// Number of connected orbs of the attribute needed to combo. NEVER_MATCH if
+// the attribute can't be matched at all.
+func (self Board) MinimumMatchFor(attribute OrbAttribute) int {
+	if minimum, exists := self.MinimumMatches[attribute]; exists {
+		return minimum
+	}
+	return self.MinimumMatch
+}
+
+// Draws the board with three characters per space. When a space can't be
+// read back from its three characters, e.g. the orb under a cloud, every space
+// is written in markup instead so ParseBoard still gets the same board.
 func (self Board) String() string {
-	border := "++" + strings.Repeat("-", 3 * int(self.Width)) + "++\n"
-	body := ""
-	for y := uint8(0); y < self.Height; y++ {
-		body += "||"
-		for x := uint8(0); x < self.Width; x++ {
-			body += self.Slots[Pair{y, x}.ToPos(self)].String()
+	cells := make([]string, len(self.Slots))
+	cell_width := 3
+	separator := ""
+	for i, slot := range self.Slots {
+		cells[i] = slot.String()
+		if parsed, err := parseGridSpace(cells[i]); err != nil || parsed != slot {
+			separator = "|"
+		}
+	}
+	if separator != "" {
+		for i, slot := range self.Slots {
+			cells[i] = slot.Markup()
+			if len(cells[i]) > cell_width {
+				cell_width = len(cells[i])
+			}
 		}
-		body += "||\n"
+		for i, cell := range cells {
+			padding := cell_width - len(cell)
+			cells[i] = strings.Repeat(" ", padding / 2) + cell + strings.Repeat(" ", padding - padding / 2)
+		}
+	}
+
+	rows := make([]string, self.Height)
+	for y := uint8(0); y < self.Height; y++ {
+		start := int(Pair{y, 0}.ToPos(self))
+		rows[y] = "||" + strings.Join(cells[start:start + int(self.Width)], separator) + "||\n"
 	}
-	return fmt.Sprintf("%s%s%s", border, body, border)
+	// Dashes over every space and separator.
+	border := "++" + strings.Repeat("-", (cell_width + len(separator)) * int(self.Width) - len(separator)) + "++\n"
+	return fmt.Sprintf("%s%s%s", border, strings.Join(rows, ""), border)
 }
 
 func (self Board) Print() {
@@ -145,6 +239,12 @@ func (self Board) Swap(placement Pair, direction Direction) (Board, error) {
 	new_board.Slots[old_pos].Orb = temp
 	new_board.Slots[new_pos].Orb.State &= ^BLIND
 	new_board.Slots[old_pos].Orb.State &= ^BLIND
+	if self.hashed {
+		new_board.hash = self.hash ^
+			zobristOrb(int(new_pos), self.Slots[new_pos].Orb) ^ zobristOrb(int(old_pos), self.Slots[old_pos].Orb) ^
+			zobristOrb(int(new_pos), new_board.Slots[new_pos].Orb) ^ zobristOrb(int(old_pos), new_board.Slots[old_pos].Orb)
+		new_board.hashed = true
+	}
 	return new_board, nil
 }
 
@@ -164,11 +264,36 @@ func (self Board) GetCounts() map[OrbAttribute]int {
 type BoardCombo struct {
 	Attribute OrbAttribute
 	Positions []Pair
-	// IsEnhanced bool
+	// Every shape this combo qualifies for, e.g. a full row of 5 on a 5x4 board
+	// is both MATCH_ROW and MATCH_5_LINE.
+	Shapes []ComboType
+	// Number of orbs in Positions which were ENHANCED.
+	EnhancedCount int
+}
+
+// Whether the combo qualifies for enhanced orb bonuses.
+func (self BoardCombo) IsEnhanced() bool {
+	return self.EnhancedCount > 0
+}
+
+func (self BoardCombo) HasShape(shape ComboType) bool {
+	for _, candidate := range self.Shapes {
+		if candidate == shape {
+			return true
+		}
+	}
+	return false
 }
 
 func (self BoardCombo) String() string {
-	return fmt.Sprintf("%s: [%d]", AttributeToName[self.Attribute], len(self.Positions))
+	result := fmt.Sprintf("%s: [%d]", AttributeToName[self.Attribute], len(self.Positions))
+	if self.IsEnhanced() {
+		result += fmt.Sprintf(" +%d", self.EnhancedCount)
+	}
+	for _, shape := range self.Shapes {
+		result += " " + shape.String()
+	}
+	return result
 }
 
 func (self BoardCombo) Print(width uint8) {
@@ -180,19 +305,6 @@ func (self BoardCombo) Print(width uint8) {
 	fmt.Println(board)
 }
 
-// type BoardRestriction uint16
-//
-// const (
-// 	RESTRICT_4PLUS BoardRestriction = 1 << iota
-// 	RESTRICT_5PLUS
-// 	RESTRICT_FIRE
-// 	RESTRICT_WATER
-// 	RESTRICT_LIGHT
-// 	RESTRICT_DARK
-// 	RESTRICT_HEART
-// 	RESTRICT_POISON
-// )
-
 func (self Board) GetOrbAt(placement Pair) Orb {
 	// Guard Clause
 	if placement.Y >= self.Height || placement.X >= self.Width {
@@ -215,7 +327,7 @@ func (self Board) markCombos(dst []bool) {
 
 			// Unmatchable orb, ignore
 			orb := self.GetOrbAt(placement)
-			if orb.Attribute == EMPTY || orb.State & UNMATCHABLE != 0 {
+			if orb.Attribute == EMPTY || orb.Attribute == UNKNOWN || orb.State & UNMATCHABLE != 0 {
 				continue
 			}
 
@@ -305,8 +417,16 @@ func (self Board) ToPair(pos uint8) Pair {
 	return Pair{pos / self.Width, pos % self.Width}
 }
 
-// TODO: Add BoardRestriction capabilities.
+// Combos which break a BoardRestriction are still cleared, use
+// BoardRestriction.Violations on the result to check them.
 func (self Board) GetCombos() ([]BoardCombo, Board) {
+	combos, _, board := self.GetMatches()
+	return combos, board
+}
+
+// Like GetCombos, but also returns the failed matches: connected orbs which
+// are under their attribute's minimum match and are left on the board.
+func (self Board) GetMatches() ([]BoardCombo, []BoardCombo, Board) {
 	// Determine which orbs will be comboed out. Do not group them yet.
   marked_combos := make([]bool, len(self.Slots))
 	self.markCombos(marked_combos)
@@ -336,12 +456,13 @@ func (self Board) GetCombos() ([]BoardCombo, Board) {
 			}
 			new_board.Slots[placement.ToPos(new_board)].Orb.Attribute = EMPTY
 		}
-		return new_board.GetCombos()
+		return new_board.GetMatches()
 	}
 
   // Group orbs to combo out.
 	is_used := make([]bool, len(self.Slots))
 	combos := make([]BoardCombo, 0)
+	failed := make([]BoardCombo, 0)
 
   // For each orb, use a DFS to find all connected orbs.
 	for i, is_comboed := range marked_combos {
@@ -383,20 +504,32 @@ func (self Board) GetCombos() ([]BoardCombo, Board) {
 				is_used[pos] = true
 			}
 		}
+		enhanced_count := 0
+		for _, combo_placement := range placements {
+			if self.GetOrbAt(combo_placement).State & ENHANCED != 0 {
+				enhanced_count++
+			}
+		}
+		combo := BoardCombo{attribute, placements, self.getComboShapes(placements), enhanced_count}
 		// For leads such as Khepri/Keela, orb matches must contain at least
 		// MinimumMatch orbs (5/4 respectively).
-		if len(placements) < self.MinimumMatch {
+		if len(placements) < self.MinimumMatchFor(attribute) {
+			failed = append(failed, combo)
 			continue
 		}
-		combo := BoardCombo{attribute, placements}
-		// TODO: Mark the combos with special values.
 		combos = append(combos, combo)
 	}
 
-	return combos, self
+	return combos, failed, self
 }
 
 func (self Board) dropOrbs() {
+	self.dropOrbsTracked(nil)
+}
+
+// Same as dropOrbs, but moves the flags in tracked alongside their orbs.
+// A nil tracked slice is ignored.
+func (self Board) dropOrbsTracked(tracked []bool) {
 	for y := self.Height - 1; y > 0; y-- {
 		for x := uint8(0); x < self.Width; x++ {
 			if self.GetOrbAt(Pair{y, x}).Attribute != EMPTY {
@@ -409,6 +542,10 @@ func (self Board) dropOrbs() {
 				if moved_orb.Attribute != EMPTY {
 					self.Slots[pos].Orb = moved_orb
 					self.Slots[new_pos].Orb = Orb{EMPTY, 0}
+					if tracked != nil {
+						tracked[pos] = tracked[new_pos]
+						tracked[new_pos] = false
+					}
 					break
 				}
 			}
@@ -417,9 +554,25 @@ func (self Board) dropOrbs() {
 }
 
 func (self Board) GetAllCombos() []BoardCombo {
+	all_combos, _ := self.GetAllMatches()
+	return all_combos
+}
+
+// Like GetAllCombos, but also returns the failed matches left on the board
+// once cascading has finished.
+func (self Board) GetAllMatches() ([]BoardCombo, []BoardCombo) {
+	if bitboard, ok := MakeBitboard(self); ok {
+		return bitboard.GetAllMatches()
+	}
+	return self.getAllMatchesBySlots()
+}
+
+// GetAllMatches for any board, cascading with GetMatches on the slots.
+func (self Board) getAllMatchesBySlots() ([]BoardCombo, []BoardCombo) {
 	all_combos := make([]BoardCombo, 0)
 	current_board := self.Clone()
-	for new_combos, current_board := current_board.GetCombos(); len(new_combos) > 0; new_combos, current_board = current_board.GetCombos() {
+	new_combos, failed, current_board := current_board.GetMatches()
+	for ; len(new_combos) > 0; new_combos, failed, current_board = current_board.GetMatches() {
 		all_combos = append(all_combos, new_combos...)
 		for _, combo := range new_combos {
 			for _, placement := range combo.Positions {
@@ -428,11 +581,39 @@ func (self Board) GetAllCombos() []BoardCombo {
 		}
 		current_board.dropOrbs()
 	}
-	return all_combos
+	return all_combos, failed
+}
+
+// One round of matching while cascading.
+type CascadeStep struct {
+	// The board before the combos are cleared.
+	Board Board
+	Combos []BoardCombo
+}
+
+// Same as GetAllCombos, but keeps the board and combos of every round.
+func (self Board) GetCascadeSteps() []CascadeStep {
+	steps := make([]CascadeStep, 0)
+	current_board := self.Clone()
+	for {
+		new_combos, next_board := current_board.GetCombos()
+		if len(new_combos) == 0 {
+			break
+		}
+		steps = append(steps, CascadeStep{next_board.Clone(), new_combos})
+		current_board = next_board
+		for _, combo := range new_combos {
+			for _, placement := range combo.Positions {
+				current_board.Slots[placement.ToPos(current_board)].Orb.Attribute = EMPTY
+			}
+		}
+		current_board.dropOrbs()
+	}
+	return steps
 }
 
 func CreateEmptyBoard(width uint8) Board {
-	return Board{make([]BoardSpace, width * (width - 1)), width - 1, width, 3}
+	return Board{make([]BoardSpace, width * (width - 1)), width - 1, width, 3, nil, 0, false}
 }
 
 func CreateRandomBoard(width uint8) Board {
@@ -441,13 +622,29 @@ func CreateRandomBoard(width uint8) Board {
 	for i := uint8(0); i < size; i++ {
 		slots[i].Orb.Attribute = OrbAttribute(uint8(rand.Intn(6) + 1))
 	}
-	return Board{slots, width - 1, width, 3}
+	return Board{slots, width - 1, width, 3, nil, 0, false}
 }
 
+// Creates a board from attribute letters. A '+' following a letter marks that
+// orb as enhanced.
 func CreateBoard(s string, width int) Board {
-  slots := make([]BoardSpace, len(s))
-	for i, rune := range s {
-		slots[i].Orb.Attribute = LetterToAttribute[string(rune)]
+  slots := make([]BoardSpace, 0, len(s))
+	for _, rune := range s {
+		if rune == '+' && len(slots) > 0 {
+			slots[len(slots) - 1].Orb.State |= ENHANCED
+			continue
+		}
+		slot := BoardSpace{}
+		slot.Orb.Attribute = LetterToAttribute[string(rune)]
+		slots = append(slots, slot)
+	}
+	return Board{slots, uint8(len(slots) / width), uint8(width), 3, nil, 0, false}
+}
+
+func CountEnhanced(combos []BoardCombo) int {
+	total := 0
+	for _, combo := range combos {
+		total += combo.EnhancedCount
 	}
-	return Board{slots, uint8(len(s) / width), uint8(width), 3}
+	return total
 }
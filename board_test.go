@@ -124,3 +124,91 @@ func TestBicolor(t *testing.T) {
 		t.Errorf("This should be a 6 combo board got %d", len(combos))
 	}
 }
+
+func TestEnhancedOrbs_CountedInCombos(t *testing.T) {
+	// R+R R+B B B
+	// G G G+L L L
+	board := CreateBoard("R+RR+BBBGGG+LLLHDHDHDDHDHDHHDHDHD", 6)
+
+	combos, _ := board.GetCombos()
+	enhanced := map[OrbAttribute]int{}
+	for _, combo := range combos {
+		enhanced[combo.Attribute] = combo.EnhancedCount
+	}
+
+	if enhanced[FIRE] != 2 || enhanced[WOOD] != 1 || enhanced[WATER] != 0 {
+		t.Errorf("Unexpected enhanced counts: %v", combos)
+	}
+	if CountEnhanced(combos) != 3 {
+		t.Errorf("Expected 3 enhanced orbs, got %d", CountEnhanced(combos))
+	}
+}
+
+func TestEnhancedOrbs_KeptThroughSwap(t *testing.T) {
+	board := CreateBoard("R+BRRGGHDHDHDDHDHDHHDHDHDGGLLBB", 6)
+
+	swapped, _ := board.Swap(Pair{0, 0}, RIGHT)
+	combos, _ := swapped.GetCombos()
+
+	if len(combos) == 0 || !combos[0].IsEnhanced() {
+		t.Errorf("Enhanced fire should be matched after swap: %v", combos)
+	}
+}
+
+func TestParseMinimumMatches(t *testing.T) {
+	minimum, overrides, err := ParseMinimumMatches("4,H=5,J=none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minimum != 4 || overrides[HEART] != 5 || overrides[JAMMER] != NEVER_MATCH || len(overrides) != 2 {
+		t.Errorf("Unexpected minimum matches: %d %v", minimum, overrides)
+	}
+	if _, _, err := ParseMinimumMatches("X=5"); err == nil {
+		t.Error("Unknown attributes should error.")
+	}
+	if _, _, err := ParseMinimumMatches("H=five"); err == nil {
+		t.Error("Invalid numbers should error.")
+	}
+	for _, invalid := range []string{"0", "-4", "2", "H=1", "H=0", "4,R=-1"} {
+		if _, _, err := ParseMinimumMatches(invalid); err == nil {
+			t.Errorf("Minimum matches below 3 should error: %s", invalid)
+		}
+	}
+	if minimum, overrides, err := ParseMinimumMatches("3,H=3"); err != nil || minimum != 3 || overrides[HEART] != 3 {
+		t.Errorf("Minimum matches of 3 should be allowed: %d %v %v", minimum, overrides, err)
+	}
+}
+
+func TestMinimumMatches_PerAttribute(t *testing.T) {
+	// H H H H . .
+	// R R R . . .
+	// J J J J J .
+	board := createShapeBoard("HHHH..", "RRR...", "JJJJJ.", "......", "......")
+	board.MinimumMatches = map[OrbAttribute]int{HEART: 5, JAMMER: NEVER_MATCH}
+
+	combos, failed, _ := board.GetMatches()
+	if len(combos) != 1 || combos[0].Attribute != FIRE {
+		t.Errorf("Only fire should combo: %v", combos)
+	}
+	if len(failed) != 2 || failed[0].Attribute != HEART || failed[1].Attribute != JAMMER {
+		t.Errorf("Heart and Jammer should fail: %v", failed)
+	}
+
+	all_combos, all_failed := board.GetAllMatches()
+	if len(all_combos) != 1 || len(all_failed) != 2 {
+		t.Errorf("Expected 1 combo and 2 failed matches, got %v and %v", all_combos, all_failed)
+	}
+	if len(board.Clone().GetAllCombos()) != 1 {
+		t.Error("Clones should keep the minimum matches.")
+	}
+}
+
+func TestGetCascadeSteps_MatchesGetAllCombos(t *testing.T) {
+	total := 0
+	for _, step := range swng_board.GetCascadeSteps() {
+		total += len(step.Combos)
+	}
+	if total != len(swng_board.GetAllCombos()) {
+		t.Errorf("Expected %d combos, got %d", len(swng_board.GetAllCombos()), total)
+	}
+}
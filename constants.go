@@ -18,6 +18,8 @@ const (
 	POISON
 	MORTAL_POISON
 	BOMB
+	// Stands in for an orb the player can't see, see MaskHidden.
+	UNKNOWN
 )
 
 var ALL_ATTRIBUTES = []OrbAttribute{FIRE, WATER, WOOD, LIGHT, DARK, HEART, JAMMER, POISON, MORTAL_POISON, BOMB}
@@ -34,6 +36,7 @@ var AttributeToName map[OrbAttribute]string = map[OrbAttribute]string{
 	POISON: "Poison",
 	MORTAL_POISON: "Mortal Poison",
 	BOMB: "Bomb",
+	UNKNOWN: "Unknown",
 }
 
 var AttributeToLetter map[OrbAttribute]string = map[OrbAttribute]string{
@@ -48,6 +51,7 @@ var AttributeToLetter map[OrbAttribute]string = map[OrbAttribute]string{
 	POISON: "P",
 	MORTAL_POISON: "M",
 	BOMB: "o",
+	UNKNOWN: "_",
 }
 
 func (self OrbAttribute) String() string {
@@ -166,4 +170,23 @@ const (
 	MATCH_L
 	MATCH_COLUMN
 	MATCH_VDP
+	MATCH_ROW
+	MATCH_5_LINE
+	MATCH_BOX
 )
+
+var ComboTypeToName map[ComboType]string = map[ComboType]string{
+	MATCH_3: "3-Match",
+	MATCH_TPA: "TPA",
+	MATCH_CROSS: "Cross",
+	MATCH_L: "L",
+	MATCH_COLUMN: "Column",
+	MATCH_VDP: "VDP",
+	MATCH_ROW: "Row",
+	MATCH_5_LINE: "5-Line",
+	MATCH_BOX: "Box",
+}
+
+func (self ComboType) String() string {
+	return ComboTypeToName[self]
+}
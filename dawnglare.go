@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Direction of a single step from its change in row and column.
+func DirectionFromDelta(dy int, dx int) (Direction, error) {
+	switch {
+	case dy == -1 && dx == 0:
+		return UP, nil
+	case dy == -1 && dx == 1:
+		return UP_RIGHT, nil
+	case dy == 0 && dx == 1:
+		return RIGHT, nil
+	case dy == 1 && dx == 1:
+		return DOWN_RIGHT, nil
+	case dy == 1 && dx == 0:
+		return DOWN, nil
+	case dy == 1 && dx == -1:
+		return DOWN_LEFT, nil
+	case dy == 0 && dx == -1:
+		return LEFT, nil
+	case dy == -1 && dx == -1:
+		return UP_LEFT, nil
+	}
+	return 0, fmt.Errorf("(%d,%d) is not a single step", dy, dx)
+}
+
+func parseDawnglareDimension(query url.Values, name string) (int, error) {
+	value, err := strconv.Atoi(query.Get(name))
+	if err != nil || value <= 0 || value > MAX_BOARD_SPACES {
+		return 0, fmt.Errorf("Invalid %s \"%s\"", name, query.Get(name))
+	}
+	return value, nil
+}
+
+// Reconstructs the board and moves from a link made by ToDawnglare. Moves is
+// empty if the link has no replay.
+func FromDawnglare(link string) (Board, Moves, error) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return Board{}, Moves{}, err
+	}
+	query := parsed.Query()
+	height, err := parseDawnglareDimension(query, "height")
+	if err != nil {
+		return Board{}, Moves{}, err
+	}
+	width, err := parseDawnglareDimension(query, "width")
+	if err != nil {
+		return Board{}, Moves{}, err
+	}
+
+	if height * width > MAX_BOARD_SPACES {
+		return Board{}, Moves{}, fmt.Errorf("%dx%d boards have more than %d spaces", height, width, MAX_BOARD_SPACES)
+	}
+
+	pattern := query.Get("patt")
+	if len(pattern) != height * width {
+		return Board{}, Moves{}, fmt.Errorf("Pattern has %d orbs, expected %d", len(pattern), height * width)
+	}
+	slots := make([]BoardSpace, len(pattern))
+	for i, letter := range pattern {
+		attribute, exists := LetterToAttribute[string(letter)]
+		if !exists || attribute == EMPTY || attribute == UNKNOWN {
+			return Board{}, Moves{}, fmt.Errorf("Unknown orb \"%c\" at %d", letter, i)
+		}
+		slots[i].Orb.Attribute = attribute
+	}
+	board := Board{slots, uint8(height), uint8(width), 3, nil, 0, false}
+
+	moves := Moves{Directions: make([]Direction, 0)}
+	replay := query.Get("replay")
+	if replay == "" {
+		return board, moves, nil
+	}
+	var last Pair
+	for i, value := range strings.Split(replay, "|") {
+		pos, err := strconv.Atoi(value)
+		if err != nil || pos < 0 || pos >= len(slots) {
+			return Board{}, Moves{}, fmt.Errorf("Invalid replay position \"%s\"", value)
+		}
+		placement := board.ToPair(uint8(pos))
+		if i == 0 {
+			moves.StartingPosition = placement
+		} else {
+			direction, err := DirectionFromDelta(int(placement.Y) - int(last.Y), int(placement.X) - int(last.X))
+			if err != nil {
+				return Board{}, Moves{}, fmt.Errorf("Replay positions %s and %s are not adjacent", last, placement)
+			}
+			moves.Directions = append(moves.Directions, direction)
+		}
+		last = placement
+	}
+	return board, moves, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromDawnglare_RoundTripsToDawnglare(t *testing.T) {
+	moves := Moves{Pair{3, 0}, []Direction{UP, UP_RIGHT, RIGHT, DOWN_RIGHT, DOWN, DOWN_LEFT, LEFT, UP_LEFT}}
+
+	board, parsed_moves, err := FromDawnglare(ToDawnglare(swng_board, moves))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.SimpleString() != swng_board.SimpleString() || board.Height != 5 || board.Width != 6 {
+		t.Errorf("Expected\n%s\ngot\n%s", swng_board, board)
+	}
+	if parsed_moves.String() != moves.String() {
+		t.Errorf("Expected %s, got %s", moves, parsed_moves)
+	}
+}
+
+func TestFromDawnglare_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+	}{
+		{"Missing height", "https://pad.dawnglare.com/?width=3&patt=RRRBBB"},
+		{"Short pattern", "https://pad.dawnglare.com/?height=2&width=3&patt=RRRBB"},
+		{"Unknown orb", "https://pad.dawnglare.com/?height=2&width=3&patt=RRRBBX"},
+		{"Not adjacent", "https://pad.dawnglare.com/?height=2&width=3&patt=RRRBBB&replay=0|2"},
+		{"Off board", "https://pad.dawnglare.com/?height=2&width=3&patt=RRRBBB&replay=0|6"},
+		{"Too many spaces", "https://pad.dawnglare.com/?height=20&width=13&patt=" + strings.Repeat("R", 260) + "&replay=0|1"},
+		{"Too tall", "https://pad.dawnglare.com/?height=300&width=1&patt=" + strings.Repeat("R", 300)},
+		{"256 tall", "https://pad.dawnglare.com/?height=256&width=1&patt=" + strings.Repeat("R", 256)},
+		{"256 spaces", "https://pad.dawnglare.com/?height=16&width=16&patt=" + strings.Repeat("R", 256)},
+	}
+
+	for _, test := range tests {
+		if _, _, err := FromDawnglare(test.link); err == nil {
+			t.Errorf("%s: expected an error for %s", test.name, test.link)
+		}
+	}
+}
+
+func TestFromDawnglare_LargestBoard(t *testing.T) {
+	board, _, err := FromDawnglare("https://pad.dawnglare.com/?height=255&width=1&patt=" + strings.Repeat("R", 255))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.Height != 255 || board.Width != 1 {
+		t.Errorf("Expected a 255x1 board, got %dx%d", board.Height, board.Width)
+	}
+	if combos := board.GetAllCombos(); len(combos) != 1 || len(combos[0].Positions) != 255 {
+		t.Errorf("Expected every orb in one combo, got %v", combos)
+	}
+}
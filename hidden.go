@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Whether the player can't see the orb in this space.
+func (self BoardSpace) IsHidden() bool {
+	return self.State & CLOUD != 0 || self.Orb.IsBlinded()
+}
+
+// Replaces every hidden orb with an UNKNOWN orb, so the solver only sees what
+// the player sees. UNKNOWN orbs never match, so solving the masked board
+// avoids relying on hidden orbs. Hidden spaces are never left empty, since
+// orbs above them would fall through while cascading.
+func (self Board) MaskHidden() Board {
+	masked := self.Clone()
+	for i := 0; i < len(masked.Slots); i++ {
+		if masked.Slots[i].IsHidden() {
+			masked.Slots[i].Orb.Attribute = UNKNOWN
+		}
+	}
+	return masked
+}
+
+// Orbs which could be hidden on the board, given the total number of orbs of
+// each attribute. Totals are usually shown in-game when the board is
+// generated. Attributes with fewer totals than are visible are ignored.
+func (self Board) HiddenPool(totals map[OrbAttribute]int) []OrbAttribute {
+	visible := self.GetCounts()
+	pool := make([]OrbAttribute, 0)
+	for _, attribute := range ALL_ATTRIBUTES {
+		for i := visible[attribute]; i < totals[attribute]; i++ {
+			pool = append(pool, attribute)
+		}
+	}
+	return pool
+}
+
+// Replaces every UNKNOWN orb with one drawn from the pool without replacement.
+// Once the pool runs out, orbs are drawn evenly from the normal orbs.
+func (self Board) RevealUnknown(pool []OrbAttribute, rng *rand.Rand) Board {
+	revealed := self.Clone()
+	shuffled := make([]OrbAttribute, len(pool))
+	copy(shuffled, pool)
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	for i := 0; i < len(revealed.Slots); i++ {
+		if revealed.Slots[i].Orb.Attribute != UNKNOWN {
+			continue
+		}
+		if len(shuffled) > 0 {
+			revealed.Slots[i].Orb.Attribute = shuffled[0]
+			shuffled = shuffled[1:]
+		} else {
+			revealed.Slots[i].Orb.Attribute = NormalOrbs[rng.Intn(len(NormalOrbs))]
+		}
+	}
+	return revealed
+}
+
+// Average number of combos over samples of the UNKNOWN orbs. The same board,
+// pool and seed always give the same value.
+func (self Board) ExpectedCombos(pool []OrbAttribute, samples int, seed int64) float64 {
+	has_unknown := false
+	for _, slot := range self.Slots {
+		has_unknown = has_unknown || slot.Orb.Attribute == UNKNOWN
+	}
+	if !has_unknown || samples <= 0 {
+		return float64(len(self.GetAllCombos()))
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	total := 0
+	for i := 0; i < samples; i++ {
+		total += len(self.RevealUnknown(pool, rng).GetAllCombos())
+	}
+	return float64(total) / float64(samples)
+}
+
+// Creates a ScoreState function for masked boards which scores by expected
+// combos over the possible hidden orbs. Like MakeExpectedValueScoreFunction,
+// values are cached per board hash and not safe to share between concurrent
+// solves.
+func MakeHiddenScoreFunction(pool []OrbAttribute, samples int, seed int64,
+                             combo_weight int, move_weight int) func(AStarState) int {
+	known_combos := MakeScoreCache(SCORE_CACHE_BITS)
+	score_fn := func(state AStarState) int {
+		hash := state.board.Hash()
+		expected, exists := known_combos.Get(hash)
+		if !exists {
+			expected = state.board.ExpectedCombos(pool, samples, seed)
+			known_combos.Set(hash, expected)
+		}
+		return int(expected * float64(combo_weight)) - (move_weight * MoveCost(state.moves))
+	}
+	return score_fn
+}
+
+// Parses orb totals such as "R=6,B=5,H=4".
+func ParseOrbCounts(s string) (map[OrbAttribute]int, error) {
+	counts := map[OrbAttribute]int{}
+	if s == "" {
+		return counts, nil
+	}
+	for _, spec := range strings.Split(s, ",") {
+		letter_value := strings.SplitN(strings.TrimSpace(spec), "=", 2)
+		if len(letter_value) != 2 {
+			return counts, fmt.Errorf("Invalid orb count \"%s\"", spec)
+		}
+		attribute, exists := LetterToAttribute[letter_value[0]]
+		if !exists || attribute == EMPTY || attribute == UNKNOWN {
+			return counts, fmt.Errorf("Unknown attribute \"%s\"", letter_value[0])
+		}
+		count, err := strconv.Atoi(letter_value[1])
+		if err != nil {
+			return counts, fmt.Errorf("Invalid orb count \"%s\"", spec)
+		}
+		counts[attribute] = count
+	}
+	return counts, nil
+}
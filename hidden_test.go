@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMaskHidden_CloudAndBlind(t *testing.T) {
+	// R R {R} B B ?B
+	board, _ := ParseBoard("RR{R}BB?B", 6)
+
+	if len(board.GetAllCombos()) != 2 {
+		t.Fatalf("The real board should have 2 combos:\n%s", board)
+	}
+	masked := board.MaskHidden()
+	if masked.Slots[2].Orb.Attribute != UNKNOWN || masked.Slots[5].Orb.Attribute != UNKNOWN {
+		t.Errorf("Hidden orbs should be unknown:\n%s", masked)
+	}
+	if len(masked.GetAllCombos()) != 0 {
+		t.Errorf("Unknown orbs should never match:\n%s", masked)
+	}
+}
+
+func TestMaskHidden_HiddenEmptyDoesNotCascade(t *testing.T) {
+	// R . .
+	// {%} . .
+	// R G B
+	// R B G
+	// G G G
+	// The cloud must stay an orb, or the top R would fall into it and match.
+	board, err := ParseBoard("R..{%}..RGBRBGGGG", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.Slots[3].Orb.Attribute = EMPTY
+	masked := board.MaskHidden()
+	if masked.Slots[3].Orb.Attribute != UNKNOWN {
+		t.Errorf("Expected the hidden hole to be unknown:\n%s", masked)
+	}
+	if combos := masked.GetAllCombos(); len(combos) != 1 {
+		t.Errorf("Expected only the Wood combo, got %v", combos)
+	}
+
+	parsed, _ := ParseBoard("R..{%}..RGBRBGGGG", 3)
+	if parsed.Slots[3].Orb.Attribute != UNKNOWN || len(parsed.GetAllCombos()) != 1 {
+		t.Errorf("Expected {%%} to parse as an unknown orb:\n%s", parsed)
+	}
+}
+
+func TestHiddenPool_UsesTotals(t *testing.T) {
+	board, _ := ParseBoard("RR{R}BB?B", 6)
+	pool := board.MaskHidden().HiddenPool(map[OrbAttribute]int{FIRE: 3, WATER: 3})
+
+	if len(pool) != 2 || pool[0] != FIRE || pool[1] != WATER {
+		t.Errorf("Expected one hidden Fire and Water, got %v", pool)
+	}
+	revealed := board.MaskHidden().RevealUnknown(pool, rand.New(rand.NewSource(0)))
+	counts := revealed.GetCounts()
+	if counts[FIRE] != 3 || counts[WATER] != 3 {
+		t.Errorf("Revealed board should keep the totals:\n%s", revealed)
+	}
+}
+
+func TestExpectedCombos_AveragesSamples(t *testing.T) {
+	board, _ := ParseBoard("RR{R}BB?B", 6)
+	masked := board.MaskHidden()
+
+	certain := masked.ExpectedCombos([]OrbAttribute{FIRE, FIRE}, 10, 1)
+	if certain != 1 {
+		t.Errorf("Only Fire can be hidden, expected 1 combo, got %.2f", certain)
+	}
+	expected := masked.ExpectedCombos([]OrbAttribute{FIRE, WATER}, 50, 1)
+	if expected <= 0 || expected >= 2 || expected != masked.ExpectedCombos([]OrbAttribute{FIRE, WATER}, 50, 1) {
+		t.Errorf("Expected a repeatable value between 0 and 2, got %.2f", expected)
+	}
+}
+
+func TestParseOrbCounts(t *testing.T) {
+	counts, err := ParseOrbCounts("R=6, H=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[FIRE] != 6 || counts[HEART] != 4 || len(counts) != 2 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+	if _, err := ParseOrbCounts("R6"); err == nil {
+		t.Error("Missing = should error.")
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Settings which only make sense on the command line: other board sources,
+// which can't be given alongside the board, and flags about what to do with
+// the solve rather than how to solve.
+var jsonExcludedSettings map[string]bool = map[string]bool{
+	"board": true,
+	"input": true,
+	"dawnglare": true,
+	"share_code": true,
+	"screenshot": true,
+	"color_profile": true,
+	"serve": true,
+	"serve_timeout_ms": true,
+	"batch": true,
+	"batch_format": true,
+	"workers": true,
+	"gif": true,
+	"interactive": true,
+	"format": true,
+	"output": true,
+	"color": true,
+}
+
+// Board and settings to solve, read by -input. Settings use the same names
+// and values as the command line flags, e.g. {"combo": 7, "diagonals": true}.
+// Other fields are ignored, so a JSONOutput can be read back as input.
+type JSONInput struct {
+	// Board markup, see markup.go.
+	Board string `json:"board"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+type JSONCombo struct {
+	Attribute string `json:"attribute"`
+	// Placements as [y, x].
+	Positions [][2]int `json:"positions"`
+	Shapes []string `json:"shapes"`
+	Enhanced int `json:"enhanced"`
+}
+
+type JSONStats struct {
+	Checked int `json:"checked"`
+	Skipped int `json:"skipped"`
+	Score int `json:"score"`
+	// Why the solve stopped, see StopReason.
+	Reason string `json:"reason"`
+	ElapsedMs int64 `json:"elapsed_ms"`
+}
+
+func MakeJSONStats(result SolveResult, elapsed time.Duration) JSONStats {
+	return JSONStats{result.Stats.Checked, result.Stats.Skipped, result.Stats.Score,
+		result.Reason.String(), elapsed.Milliseconds()}
+}
+
+// Everything about a solve, written by -output json.
+type JSONOutput struct {
+	Board string `json:"board"`
+	Width int `json:"width"`
+	Height int `json:"height"`
+	// Value of every flag, in the same format as JSONInput.Settings.
+	Settings map[string]string `json:"settings"`
+	// Placement as [y, x].
+	StartingPosition [2]int `json:"starting_position"`
+	Directions []string `json:"directions"`
+	Dawnglare string `json:"dawnglare"`
+	// Board and moves for -share_code, empty if they can't be shared.
+	ShareCode string `json:"share_code"`
+	FinalBoard string `json:"final_board"`
+	Combos []JSONCombo `json:"combos"`
+	Stats JSONStats `json:"stats"`
+}
+
+func MakeJSONCombo(combo BoardCombo) JSONCombo {
+	positions := make([][2]int, len(combo.Positions))
+	for i, placement := range combo.Positions {
+		positions[i] = [2]int{int(placement.Y), int(placement.X)}
+	}
+	shapes := make([]string, len(combo.Shapes))
+	for i, shape := range combo.Shapes {
+		shapes[i] = shape.String()
+	}
+	return JSONCombo{combo.Attribute.String(), positions, shapes, combo.EnhancedCount}
+}
+
+func MakeJSONOutput(board Board, moves Moves, final_board Board, settings map[string]string,
+                    stats JSONStats) JSONOutput {
+	directions := make([]string, len(moves.Directions))
+	for i, direction := range moves.Directions {
+		directions[i] = direction.String()
+	}
+	combos := make([]JSONCombo, 0)
+	for _, combo := range final_board.GetAllCombos() {
+		combos = append(combos, MakeJSONCombo(combo))
+	}
+	share_code, _ := EncodeShareCode(board, moves)
+	return JSONOutput{
+		Board: board.Markup(),
+		Width: int(board.Width),
+		Height: int(board.Height),
+		Settings: settings,
+		StartingPosition: [2]int{int(moves.StartingPosition.Y), int(moves.StartingPosition.X)},
+		Directions: directions,
+		Dawnglare: ToDawnglare(board, moves),
+		ShareCode: share_code,
+		FinalBoard: final_board.Markup(),
+		Combos: combos,
+		Stats: stats,
+	}
+}
+
+// Output for a solve of the input.
+func (self SolveInput) Output(result SolveResult, elapsed time.Duration) (JSONOutput, error) {
+	final_board, err := self.Board.ApplyMoves(result.Moves, self.Settings.MoveTimer)
+	if err != nil {
+		return JSONOutput{}, err
+	}
+	return MakeJSONOutput(self.Board, result.Moves, final_board, self.Values, MakeJSONStats(result, elapsed)), nil
+}
+
+func (self JSONOutput) Write(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(self)
+}
+
+// Value of every flag in the set which JSONInput accepts.
+func FlagSettings(flags *flag.FlagSet) map[string]string {
+	settings := map[string]string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		if !jsonExcludedSettings[f.Name] {
+			settings[f.Name] = f.Value.String()
+		}
+	})
+	return settings
+}
+
+// Value of every flag given on the command line which JSONInput accepts.
+func ExplicitFlagSettings(flags *flag.FlagSet) map[string]string {
+	settings := map[string]string{}
+	flags.Visit(func(f *flag.Flag) {
+		if !jsonExcludedSettings[f.Name] {
+			settings[f.Name] = f.Value.String()
+		}
+	})
+	return settings
+}
+
+func jsonSettingString(value interface{}) (string, error) {
+	switch typed := value.(type) {
+	case string:
+		return typed, nil
+	case bool:
+		return strconv.FormatBool(typed), nil
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("Unsupported value %v", value)
+}
+
+func ReadJSONInput(reader io.Reader) (JSONInput, error) {
+	input := JSONInput{}
+	err := json.NewDecoder(reader).Decode(&input)
+	return input, err
+}
+
+// Sets every flag named in the input which was not already given on the
+// command line, so command line flags override the file.
+func (self JSONInput) Apply(flags *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	if self.Board != "" && !explicit["board"] {
+		if err := flags.Set("board", self.Board); err != nil {
+			return err
+		}
+	}
+	for name, value := range self.Settings {
+		if jsonExcludedSettings[name] {
+			return fmt.Errorf("Setting \"%s\" is not allowed in the input file", name)
+		}
+		if flags.Lookup(name) == nil {
+			return fmt.Errorf("Unknown setting \"%s\"", name)
+		}
+		if explicit[name] {
+			continue
+		}
+		as_string, err := jsonSettingString(value)
+		if err != nil {
+			return fmt.Errorf("Setting \"%s\": %s", name, err)
+		}
+		if err := flags.Set(name, as_string); err != nil {
+			return fmt.Errorf("Setting \"%s\": %s", name, err)
+		}
+	}
+	return nil
+}
+
+func LoadJSONInput(path string, flags *flag.FlagSet) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	input, err := ReadJSONInput(file)
+	if err != nil {
+		return err
+	}
+	return input.Apply(flags)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func makeTestFlags() (*flag.FlagSet, *string, *int, *bool) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	board := flags.String("board", "", "")
+	combo := flags.Int("combo", 7, "")
+	diagonals := flags.Bool("diagonals", false, "")
+	flags.String("input", "", "")
+	return flags, board, combo, diagonals
+}
+
+func TestJSONInput_AppliesSettings(t *testing.T) {
+	flags, board, combo, diagonals := makeTestFlags()
+	flags.Parse([]string{"-combo", "9"})
+
+	input, err := ReadJSONInput(strings.NewReader(
+		`{"board": "RRB<G>", "settings": {"combo": 5, "diagonals": true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := input.Apply(flags); err != nil {
+		t.Fatal(err)
+	}
+	if *board != "RRB<G>" || !*diagonals {
+		t.Errorf("Expected the board and diagonals to be set, got %s %t", *board, *diagonals)
+	}
+	if *combo != 9 {
+		t.Errorf("The command line combo should take precedence, got %d", *combo)
+	}
+}
+
+func TestJSONInput_Errors(t *testing.T) {
+	tests := []string{
+		`{"settings": {"unknown": 1}}`,
+		`{"settings": {"combo": "many"}}`,
+		`{"settings": {"input": "other.json"}}`,
+		`{"settings": {"dawnglare": "https://pad.dawnglare.com/"}}`,
+		`{"settings": {"combo": [1, 2]}}`,
+	}
+
+	for _, test := range tests {
+		flags, _, _, _ := makeTestFlags()
+		input, err := ReadJSONInput(strings.NewReader(test))
+		if err == nil {
+			err = input.Apply(flags)
+		}
+		if err == nil {
+			t.Errorf("Expected an error for %s", test)
+		}
+	}
+}
+
+func TestJSONOutput_ReadsBackAsInput(t *testing.T) {
+	board, _ := ParseBoard("&R+RRHHDGDGGGDGDDRRRRDRGGDGRRDD{G}", 6)
+	moves := Moves{Pair{4, 5}, []Direction{UP, LEFT}}
+	final_board, _ := board.ApplyMoves(moves, MoveTimer{})
+	flags, _, _, _ := makeTestFlags()
+	flags.Set("combo", "4")
+
+	output := MakeJSONOutput(board, moves, final_board, FlagSettings(flags), JSONStats{})
+	if len(output.Combos) != len(final_board.GetAllCombos()) || output.Combos[0].Positions == nil {
+		t.Errorf("Expected every combo with positions: %v", output.Combos)
+	}
+	if output.Directions[0] != "U" || output.StartingPosition != [2]int{4, 5} {
+		t.Errorf("Unexpected moves %v from %v", output.Directions, output.StartingPosition)
+	}
+	buffer := bytes.Buffer{}
+	if err := output.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	input, err := ReadJSONInput(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	new_flags, new_board, new_combo, _ := makeTestFlags()
+	if err := input.Apply(new_flags); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseBoard(*new_board, 6)
+	if err != nil || parsed.Markup() != board.Markup() || *new_combo != 4 {
+		t.Errorf("Expected %s with combo 4, got %s with combo %d", board.Markup(), *new_board, *new_combo)
+	}
+}
+
+func TestJSONOutput_LeavesOutCommandLineOnlySettings(t *testing.T) {
+	make_flags := func() (*flag.FlagSet, *string, *string) {
+		flags, board, _, _ := makeTestFlags()
+		dawnglare := flags.String("dawnglare", "", "")
+		for _, name := range []string{"share_code", "screenshot", "format", "gif"} {
+			flags.String(name, "", "")
+		}
+		flags.Bool("interactive", false, "")
+		flags.Int("workers", 1, "")
+		return flags, board, dawnglare
+	}
+	flags, _, _ := make_flags()
+	flags.Parse([]string{"-dawnglare", "https://pad.dawnglare.com/?height=5&width=6", "-format", "json",
+		"-interactive", "-workers", "4", "-combo", "5"})
+
+	board := CreateBoard("RRRRRRBBBBBBGGGGGGLLLLLLDDDDDD", 6)
+	output := MakeJSONOutput(board, Moves{}, board, FlagSettings(flags), JSONStats{})
+	for name := range output.Settings {
+		if jsonExcludedSettings[name] {
+			t.Errorf("Expected %s to be left out of the settings", name)
+		}
+	}
+	buffer := bytes.Buffer{}
+	if err := output.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	input, err := ReadJSONInput(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	new_flags, new_board, new_dawnglare := make_flags()
+	if err := input.Apply(new_flags); err != nil {
+		t.Fatal(err)
+	}
+	// Only the board is set, so main doesn't see two board sources.
+	if *new_board != board.Markup() || *new_dawnglare != "" {
+		t.Errorf("Expected only the board to be set, got %q and %q", *new_board, *new_dawnglare)
+	}
+	if new_flags.Lookup("combo").Value.String() != "5" {
+		t.Errorf("Expected the solve settings to be read back")
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
 	"strings"
 	"strconv"
 	"time"
@@ -10,19 +14,28 @@ import (
 
 var (
 	board_to_solve Board
-	starting_placements []Pair
+	solve_settings SolveSettings
 
 	// User defined flags.
 	board_flag string
 	flag_board_width int
-	flag_combo_minimum int
-	flag_allow_diagonals bool
-	flag_combo_weight int
-	flag_move_weight int
-	flag_max_moves int
-	flag_timeout_ms int
-	flag_starting_positions string
-	flag_minimum_match int
+	solve_flags *SolveFlags
+	flag_dawnglare string
+	// Path loaded along with the board, if any.
+	imported_moves Moves
+	flag_share_code string
+	flag_screenshot string
+	flag_color_profile string
+	flag_gif string
+	flag_format string
+	flag_color string
+	flag_interactive bool
+	flag_input string
+	flag_serve string
+	flag_serve_timeout_ms int
+	flag_batch string
+	flag_batch_format string
+	flag_workers int
 )
 
 
@@ -30,57 +43,119 @@ var (
 func init() {
 	flag.IntVar(&flag_board_width, "width", 6, "Board width. Height will be (width-1)")
 	flag.StringVar(&board_flag, "board", "",
-			"Board String (R)ed, (B)lue, (G)reen, (L)ight, (D)ark, (H)eart, (P)oison), (M)ortal Poison, (J)ammer, B(o)mb.")
-	flag.IntVar(&flag_combo_minimum, "combo", 7, "Minimum number of combos to stop matching at.")
-	flag.BoolVar(&flag_allow_diagonals, "diagonals", false, "Whether to allow diagonals.")
-	flag.IntVar(&flag_combo_weight, "combo_weight", 20, "How much combos are scored relative to move count. Higher value calculates faster, lower prioritizes fewer moves.")
-	flag.IntVar(&flag_move_weight, "move_weight", 1, "How much moves cost in value for heuristic.")
-	flag.IntVar(&flag_max_moves, "max_moves", 50, "Maximum number of allowable moves.")
-	flag.IntVar(&flag_timeout_ms, "timeout_ms", -1, "How long to keep calculating (ms) before giving up. Negative is indefinite.")
-	flag.StringVar(&flag_starting_positions, "starting_positions", "", "Allowable starting positions in 0-indexed Y-X separated format. e.g. \"0,0|2,1|4,5\".")
-	flag.IntVar(&flag_minimum_match, "min_match", 3, "Minimum number of orbs connected to combo, such as Khepri. 3 or lower will not impact result.")
+			"Board String (R)ed, (B)lue, (G)reen, (L)ight, (D)ark, (H)eart, (P)oison), (M)ortal Poison, (J)ammer, B(o)mb. Follow a letter with + to mark it enhanced, precede it with & to lock it. See markup.go for tape, cloud, blind and spinner markup.")
+	solve_flags = DefineSolveFlags(flag.CommandLine)
+	flag.StringVar(&flag_dawnglare, "dawnglare", "", "Dawnglare link to load the board and path from, instead of -board.")
+	flag.StringVar(&flag_share_code, "share_code", "", "Share code to load the board and path from, instead of -board. Printed with every solution.")
+	flag.StringVar(&flag_screenshot, "screenshot", "", "PNG or JPEG screenshot to read the board from, instead of -board.")
+	flag.StringVar(&flag_color_profile, "color_profile", "", "Orb color calibration file for -screenshot, one \"<letter> <red> <green> <blue>\" per line.")
+	flag.StringVar(&flag_gif, "gif", "", "File to write an animated GIF of the solution to.")
+	flag.StringVar(&flag_format, "format", "text", "Output format of the solution, \"text\", \"svg\" or \"json\". Solver progress goes to stderr for svg and json.")
+	flag.StringVar(&flag_format, "output", "text", "Same as -format.")
+	flag.StringVar(&flag_color, "color", "auto", "Color the printed solution, \"auto\" only colors when stdout is a terminal. One of auto, always, never.")
+	flag.BoolVar(&flag_interactive, "interactive", false, "Step through the solution move by move and cascade by cascade, editing and solving the board again as needed.")
+	flag.StringVar(&flag_input, "input", "", "JSON file with the board and settings to solve, e.g. {\"board\": \"RRB...\", \"settings\": {\"combo\": 7}}. Flags given on the command line take precedence.")
+	flag.StringVar(&flag_serve, "serve", "", "Address to serve solves over HTTP on instead of solving once, e.g. \"localhost:8080\". See server.go.")
+	flag.IntVar(&flag_serve_timeout_ms, "serve_timeout_ms", 10000, "Longest a single HTTP request may solve for (ms), also used when a request has no timeout.")
+	flag.StringVar(&flag_batch, "batch", "", "File of boards to solve instead of solving once, \"-\" for stdin. One board per line, either markup or JSON like -input. Flags given on the command line are used for settings a line doesn't give.")
+	flag.StringVar(&flag_batch_format, "batch_format", "jsonl", "Output format of -batch results, \"jsonl\" or \"csv\".")
+	flag.IntVar(&flag_workers, "workers", runtime.NumCPU(), "Number of boards -batch solves at once.")
 	flag.Parse()
 
-	if flag_board_width < 5 || flag_board_width > 7 {
-		panic("Board width should be in the range [5,7]")
+	if flag_input != "" {
+		if err := LoadJSONInput(flag_input, flag.CommandLine); err != nil {
+			panic(err)
+		}
 	}
-	starting_placements = make([]Pair, 0)
-	if flag_starting_positions != "" {
-		for _, coordinate := range strings.Split(flag_starting_positions, "|") {
-			coordinate_vals := strings.Split(coordinate, ",")
-			if len(coordinate_vals) != 2 {
-				panic("Coordinate string invalid: \"" + coordinate + "\"")
-			}
-			y, err := strconv.Atoi(coordinate_vals[0])
-			if err != nil {
-				panic(err)
-			}
-			x, err := strconv.Atoi(coordinate_vals[1])
+
+	if flag_format != "text" && flag_format != "svg" && flag_format != "json" {
+		panic("Format should be one of \"text\", \"svg\" or \"json\"")
+	}
+	if flag_batch_format != "jsonl" && flag_batch_format != "csv" {
+		panic("Batch format should be one of \"jsonl\" or \"csv\"")
+	}
+	if flag_color != "auto" && flag_color != "always" && flag_color != "never" {
+		panic("Color should be one of \"auto\", \"always\" or \"never\"")
+	}
+	if flag_dawnglare != "" {
+		if board_flag != "" {
+			panic("Only one of -board and -dawnglare may be set")
+		}
+		board, moves, err := FromDawnglare(flag_dawnglare)
+		if err != nil {
+			panic(err)
+		}
+		board_to_solve = board
+		imported_moves = moves
+		flag_board_width = int(board.Width)
+	}
+	if flag_share_code != "" {
+		if board_flag != "" || flag_dawnglare != "" {
+			panic("Only one of -board, -dawnglare and -share_code may be set")
+		}
+		board, moves, err := DecodeShareCode(flag_share_code)
+		if err != nil {
+			panic(err)
+		}
+		board_to_solve = board
+		imported_moves = moves
+		flag_board_width = int(board.Width)
+	}
+	if flag_screenshot != "" {
+		if board_flag != "" || flag_dawnglare != "" || flag_share_code != "" {
+			panic("Only one of -board, -dawnglare, -share_code and -screenshot may be set")
+		}
+		profile := DefaultColorProfile
+		if flag_color_profile != "" {
+			loaded, err := LoadColorProfile(flag_color_profile)
 			if err != nil {
 				panic(err)
 			}
-			if y < 0 || y >= flag_board_width - 1 {
-				panic(fmt.Sprintf("Y value %d outside of range [0,%d]", y, flag_board_width - 1))
-			}
-			if x < 0 || x >= flag_board_width {
-				panic(fmt.Sprintf("X value %d outside of range [0,%d]", x, flag_board_width))
-			}
-			starting_placements = append(starting_placements, Pair{uint8(y), uint8(x)})
+			profile = loaded
 		}
+		recognition, err := RecognizeScreenshot(flag_screenshot, profile)
+		if err != nil {
+			panic(err)
+		}
+		for _, placement := range recognition.LowConfidence(LOW_CONFIDENCE) {
+			cell := recognition.Cells[placement.ToPos(recognition.Board)]
+			// Stderr, so the warnings never end up in svg or json output.
+			fmt.Fprintf(os.Stderr, "Unsure of %s at %s (%.0f%% confident)\n",
+				cell.Attribute, placement, cell.Confidence * 100)
+		}
+		board_to_solve = recognition.Board
+		flag_board_width = int(recognition.Board.Width)
 	}
-
-	if board_flag == "" {
+	if flag_board_width < 5 || flag_board_width > 7 {
+		panic("Board width should be in the range [5,7]")
+	}
+	if flag_dawnglare != "" || flag_share_code != "" || flag_screenshot != "" {
+		// Already loaded above, but everything else expects the usual height.
+		if int(board_to_solve.Height) != flag_board_width - 1 {
+			panic(fmt.Sprintf("Board height expected to be %d, got %d", flag_board_width - 1, board_to_solve.Height))
+		}
+	} else if board_flag == "" {
 		board_to_solve = CreateRandomBoard(uint8(flag_board_width))
 	} else {
-		if len(board_flag) != (flag_board_width * (flag_board_width - 1)) {
-			err := fmt.Sprintf("Board size expected to be %d, got %d", flag_board_width * (flag_board_width - 1), len(board_flag))
+		board, err := ParseBoard(board_flag, flag_board_width)
+		if err != nil {
 			panic(err)
 		}
-		board_to_solve = CreateBoard(board_flag, flag_board_width)
+		if int(board.Width) != flag_board_width {
+			panic(fmt.Sprintf("Board width expected to be %d, got %d", flag_board_width, board.Width))
+		}
+		if len(board.Slots) != (flag_board_width * (flag_board_width - 1)) {
+			err := fmt.Sprintf("Board size expected to be %d, got %d", flag_board_width * (flag_board_width - 1), len(board.Slots))
+			panic(err)
+		}
+		board_to_solve = board
 	}
-	if flag_minimum_match > 3 {
-		board_to_solve.MinimumMatch = flag_minimum_match
+	settings, err := solve_flags.Settings(flag_board_width)
+	if err != nil {
+		panic(err)
 	}
+	solve_settings = settings
+	board_to_solve = solve_settings.PrepareBoard(board_to_solve)
 }
 
 // Get a string that links to Dawnglare for the given board and moves.
@@ -102,73 +177,133 @@ func ToDawnglare(board Board, moves Moves) string {
 	return fmt.Sprintf(dawnglare_pattern, height, width, board.SimpleString(), move_string)
 }
 
-func MakeRejectionFunction() func(AStarState) bool {
-	known_boards := map[string]int{}
-	rejection_fn := func(state AStarState) bool {
-		key := state.current_pos.String() + state.board.SimpleString()
-		// value := state.starting_pos.String() + DirectionsToString(state.moves)
-		if old_val, exists := known_boards[key]; exists && state.score <= old_val {
-			return true
-		}
-		if len(state.moves) > flag_max_moves {
-			return true
-		}
-		known_boards[key] = state.score
-		return false
+// Solves the board with the settings from the flags, writing each new best
+// path to progress as it is found.
+func solveWithProgress(board Board, progress io.Writer) SolveResult {
+	print_improved := func(moves Moves, score int) {
+		fmt.Fprintf(progress, "Current best with score of %d\n%s\n", score, moves)
 	}
-	return rejection_fn
+	result := solve_settings.Solve(context.Background(), board, print_improved)
+	if result.Reason == TIMED_OUT {
+		fmt.Fprintf(progress, "Timed out after %dms. Returning best value.\n", solve_settings.Timeout.Milliseconds())
+	}
+	fmt.Fprintf(progress, "Finished after %d checks with %d skipped: %s.\n",
+		result.Stats.Checked, result.Stats.Skipped, result.Reason)
+	return result
 }
 
 func main() {
-	timed_out := false
-	if flag_timeout_ms > 0 {
-		go func() {
-			timeout_timer := time.NewTimer(time.Duration(flag_timeout_ms) * time.Millisecond)
-			<-timeout_timer.C
-			timed_out = true
-		}()
-	}
-	acceptance_fn := func(state AStarState) bool {
-		if timed_out {
-			fmt.Printf("Timed out after %dms. Returning best value.\n", flag_timeout_ms)
-			return true
-		}
-		return len(state.board.GetAllCombos()) >= flag_combo_minimum
-	}
-	scoring_fn := func(state AStarState) int {
-		move_cost := 3
-		total_cost := 3
-		last_move := state.moves[0]
-		for _, move := range state.moves[1:] {
-			if move == last_move {
-				if move_cost > 1 {
-					move_cost--
-				}
-			} else {
-				move_cost = 3
+	if flag_serve != "" {
+		server := SolveServer{time.Duration(flag_serve_timeout_ms) * time.Millisecond}
+		fmt.Printf("Serving on %s\n", flag_serve)
+		panic(server.ListenAndServe(flag_serve))
+	}
+	if flag_batch != "" {
+		output := os.Stdout
+		input := os.Stdin
+		if flag_batch != "-" {
+			file, err := os.Open(flag_batch)
+			if err != nil {
+				panic(err)
 			}
-			last_move = move
-			total_cost += move_cost
+			defer file.Close()
+			input = file
+		}
+		write := MakeJSONLBatchWriter(output)
+		if flag_batch_format == "csv" {
+			write = MakeCSVBatchWriter(output)
 		}
-		return len(state.board.GetAllCombos()) * flag_combo_weight - (flag_move_weight * total_cost)
+		err := SolveBatch(context.Background(), input, ExplicitFlagSettings(flag.CommandLine), flag_workers, write)
+		if err != nil {
+			panic(err)
+		}
+		return
 	}
+	move_timer := solve_settings.MoveTimer
+	time_budget := solve_settings.TimeBudget
 
-	requirement := SolveRequirement{
-		flag_allow_diagonals,
-		// Determines if a state meets the goal.
-		acceptance_fn,
-		// Determines if a state should be ignored.
-		MakeRejectionFunction(),
-		// Determines and updates a state's score.
-		scoring_fn,
-		// Allowable starting positions. If empty, search all.
-		starting_placements,
+	// Keep stdout for the SVG or JSON alone, everything printed while solving
+	// is only progress.
+	output := os.Stdout
+	progress := io.Writer(os.Stdout)
+	if flag_format == "svg" || flag_format == "json" {
+		progress = os.Stderr
 	}
 
-	fmt.Printf("Solving board:\n%s", board_to_solve)
+	fmt.Fprintf(progress, "Solving board:\n%s", board_to_solve)
+	if len(imported_moves.Directions) > 0 {
+		imported_board, err := board_to_solve.ApplyMoves(imported_moves, move_timer)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(progress, "Imported path %s makes %d combos.\n", imported_moves, len(imported_board.GetAllCombos()))
+	}
 
-	moves := AStarSolve(board_to_solve, requirement)
+	start_time := time.Now()
+	result := solveWithProgress(board_to_solve, progress)
+	elapsed := time.Since(start_time)
+	moves := result.Moves
 
-	fmt.Println(moves)
-	fmt.Println(ToDawnglare(board_to_solve, moves))
+	fmt.Fprintln(progress, moves)
+	if time_budget.Enabled() {
+		fmt.Fprintf(progress, "Estimated time: %.2fs of %.2fs\n",
+			move_timer.PathSeconds(moves.Directions), time_budget.Seconds())
+	} else {
+		fmt.Fprintf(progress, "Estimated time: %.2fs\n", move_timer.PathSeconds(moves.Directions))
+	}
+	fmt.Fprintln(progress, ToDawnglare(board_to_solve, moves))
+	if share_code, err := EncodeShareCode(board_to_solve, moves); err == nil {
+		fmt.Fprintf(progress, "Share code: %s\n", share_code)
+	}
+	if flag_format == "text" {
+		renderer := MakeANSIRenderer(output)
+		if flag_color != "auto" {
+			renderer.Color = flag_color == "always"
+		}
+		final_board, err := board_to_solve.ApplyMoves(moves, move_timer)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprint(output, renderer.Path(board_to_solve, moves))
+		fmt.Fprint(output, renderer.CascadeSteps(final_board.GetCascadeSteps()))
+	}
+	if flag_format == "json" {
+		final_board, err := board_to_solve.ApplyMoves(moves, move_timer)
+		if err != nil {
+			panic(err)
+		}
+		json_output := MakeJSONOutput(board_to_solve, moves, final_board, FlagSettings(flag.CommandLine),
+			MakeJSONStats(result, elapsed))
+		if err := json_output.Write(output); err != nil {
+			panic(err)
+		}
+	}
+	if flag_format == "svg" {
+		if err := RenderSolutionSVG(board_to_solve, moves, move_timer, output); err != nil {
+			panic(err)
+		}
+	}
+	if flag_gif != "" {
+		file, err := os.Create(flag_gif)
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+		if err := RenderSolutionGIF(board_to_solve, moves, move_timer, file); err != nil {
+			panic(err)
+		}
+	}
+	if flag_interactive {
+		renderer := MakeANSIRenderer(output)
+		if flag_color != "auto" {
+			renderer.Color = flag_color == "always"
+		}
+		solve := func(board Board) Moves {
+			return solveWithProgress(board, progress).Moves
+		}
+		session := MakeSession(board_to_solve, moves, move_timer, solve, renderer)
+		if err := session.Run(os.Stdin, output); err != nil {
+			panic(err)
+		}
+	}
 }
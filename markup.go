@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Board markup describes one board space per token. Tokens are written back to
+// back, e.g. "RRH&R+<G>{%}..." for a 6 wide board.
+//
+// Orbs:
+//  * R B G L D H J P M o: The attribute letter, see AttributeToLetter.
+//  * . or space: An empty slot.
+//  * _: An unknown orb, see MaskHidden.
+//  * &X: Locked orb.
+//  * X+: Enhanced orb.
+//  * ?X: Blinded orb. A lone ? is a blinded UNKNOWN orb.
+//  * !X: Sticky blinded orb.
+//  * ~X: Unmatchable orb.
+// Prefixes combine in that order, e.g. &~R+ is a locked, unmatchable,
+// enhanced Fire orb.
+//
+// Board spaces wrap an orb:
+//  * <X>: Tape, the orb can't be moved.
+//  * {X}: Cloud over the orb. {%} is a cloud over an UNKNOWN orb.
+//  * [X]: Spinner which changes its orb every second.
+//  * (X): Spinner which changes its orb every two seconds.
+//
+// Board.Markup() writes this format, and ParseBoard reads it back to the same
+// board. ParseBoard also reads back the output of Board.String(). Its rows sit
+// between || borders with every space three characters wide, unless a space
+// doesn't fit in three characters, e.g. the orb under a cloud. Then every
+// space is written in markup, separated by | and padded with spaces.
+
+var spaceMarkupClose map[rune]rune = map[rune]rune{
+	'<': '>',
+	'{': '}',
+	'[': ']',
+	'(': ')',
+}
+
+var spaceMarkupState map[rune]BoardSpaceStateFlag = map[rune]BoardSpaceStateFlag{
+	'<': TAPE,
+	'{': CLOUD,
+	'[': SPINNER_1S,
+	'(': SPINNER_2S,
+}
+
+type markupParser struct {
+	runes []rune
+	pos int
+}
+
+func (self *markupParser) done() bool {
+	return self.pos >= len(self.runes)
+}
+
+func (self *markupParser) peek() rune {
+	if self.done() {
+		return 0
+	}
+	return self.runes[self.pos]
+}
+
+func (self *markupParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Position %d: %s", self.pos, fmt.Sprintf(format, args...))
+}
+
+func (self *markupParser) parseOrb() (Orb, error) {
+	orb := Orb{EMPTY, 0}
+	if self.peek() == '&' {
+		orb.State |= LOCKED
+		self.pos++
+	}
+	if self.peek() == '~' {
+		orb.State |= UNMATCHABLE
+		self.pos++
+	}
+	switch self.peek() {
+	case '?':
+		orb.State |= BLIND
+		self.pos++
+	case '!':
+		orb.State |= STICKY_BLIND
+		self.pos++
+	}
+
+	letter := self.peek()
+	if letter == '.' {
+		letter = ' '
+	}
+	if attribute, exists := LetterToAttribute[string(letter)]; exists && !self.done() {
+		orb.Attribute = attribute
+		self.pos++
+	} else if orb.State & BLIND != 0 {
+		// Only a plain blind orb may hide its attribute.
+		orb.Attribute = UNKNOWN
+	} else {
+		if self.done() {
+			return orb, self.errorf("Expected an orb, got end of board")
+		}
+		return orb, self.errorf("Unknown orb \"%c\"", self.peek())
+	}
+
+	if self.peek() == '+' {
+		orb.State |= ENHANCED
+		self.pos++
+	}
+	return orb, nil
+}
+
+func (self *markupParser) parseSpace() (BoardSpace, error) {
+	space := BoardSpace{}
+	open := self.peek()
+	close, is_wrapped := spaceMarkupClose[open]
+	if !is_wrapped {
+		orb, err := self.parseOrb()
+		space.Orb = orb
+		return space, err
+	}
+	self.pos++
+	space.State |= spaceMarkupState[open]
+
+	if open == '{' && self.peek() == '%' {
+		space.Orb.Attribute = UNKNOWN
+		self.pos++
+	} else {
+		orb, err := self.parseOrb()
+		if err != nil {
+			return space, err
+		}
+		space.Orb = orb
+	}
+	if self.peek() != close {
+		return space, self.errorf("Expected \"%c\" to close \"%c\"", close, open)
+	}
+	self.pos++
+	return space, nil
+}
+
+// Parses a single space written in markup, e.g. "&R+" or "<G>".
+func ParseSpace(token string) (BoardSpace, error) {
+	parser := markupParser{[]rune(token), 0}
+	space, err := parser.parseSpace()
+	if err == nil && !parser.done() {
+		err = parser.errorf("Unexpected \"%c\" after space", parser.peek())
+	}
+	return space, err
+}
+
+// Parses a single three character space from Board.String().
+func parseGridSpace(cell string) (BoardSpace, error) {
+	runes := []rune(cell)
+	if len(runes) != 3 {
+		return BoardSpace{}, fmt.Errorf("Space \"%s\" should be 3 characters", cell)
+	}
+	if _, is_wrapped := spaceMarkupClose[runes[0]]; is_wrapped {
+		parser := markupParser{runes, 0}
+		space, err := parser.parseSpace()
+		if err == nil && !parser.done() {
+			err = fmt.Errorf("Unexpected \"%c\" in space \"%s\"", parser.peek(), cell)
+		}
+		return space, err
+	}
+
+	space := BoardSpace{}
+	switch runes[0] {
+	case ' ':
+	case '&':
+		space.Orb.State |= LOCKED
+	default:
+		return space, fmt.Errorf("Unknown lock marker \"%c\" in space \"%s\"", runes[0], cell)
+	}
+	if runes[1] == '?' {
+		space.Orb = Orb{UNKNOWN, space.Orb.State | BLIND}
+	} else if attribute, exists := LetterToAttribute[string(runes[1])]; exists {
+		space.Orb.Attribute = attribute
+	} else {
+		return space, fmt.Errorf("Unknown orb \"%c\" in space \"%s\"", runes[1], cell)
+	}
+	switch runes[2] {
+	case ' ':
+	case '+':
+		space.Orb.State |= ENHANCED
+	default:
+		return space, fmt.Errorf("Unknown enhance marker \"%c\" in space \"%s\"", runes[2], cell)
+	}
+	return space, nil
+}
+
+// Parses one row between the || borders of Board.String().
+func parseGridRow(row string) ([]BoardSpace, error) {
+	spaces := make([]BoardSpace, 0)
+	if strings.Contains(row, "|") {
+		for _, cell := range strings.Split(row, "|") {
+			token := strings.Trim(cell, " ")
+			if token == "" {
+				return nil, fmt.Errorf("Missing space in \"%s\"", row)
+			}
+			space, err := ParseSpace(token)
+			if err != nil {
+				return nil, err
+			}
+			spaces = append(spaces, space)
+		}
+		return spaces, nil
+	}
+
+	runes := []rune(row)
+	if len(runes) % 3 != 0 {
+		return nil, fmt.Errorf("Partial space in \"%s\"", row)
+	}
+	for i := 0; i < len(runes); i += 3 {
+		space, err := parseGridSpace(string(runes[i:i + 3]))
+		if err != nil {
+			return nil, err
+		}
+		spaces = append(spaces, space)
+	}
+	return spaces, nil
+}
+
+func parseGrid(s string) (Board, error) {
+	slots := make([]BoardSpace, 0)
+	width := 0
+	height := 0
+	for _, line := range strings.Split(s, "\n") {
+		if !strings.HasPrefix(line, "||") {
+			continue
+		}
+		row, err := parseGridRow(strings.TrimSuffix(strings.TrimPrefix(line, "||"), "||"))
+		if err != nil {
+			return Board{}, fmt.Errorf("Row %d: %s", height, err)
+		}
+		if height == 0 {
+			width = len(row)
+		} else if len(row) != width {
+			return Board{}, fmt.Errorf("Row %d has width %d, expected %d", height, len(row), width)
+		}
+		slots = append(slots, row...)
+		height++
+		// Keeps both dimensions within a uint8 too.
+		if len(slots) > MAX_BOARD_SPACES {
+			return Board{}, fmt.Errorf("Boards can't have more than %d spaces", MAX_BOARD_SPACES)
+		}
+	}
+	if height == 0 {
+		return Board{}, fmt.Errorf("No rows found")
+	}
+	return Board{slots, uint8(height), uint8(width), 3, nil, 0, false}, nil
+}
+
+// Parses a board from markup, see the top of this file. The Board.String()
+// format carries its own width, which has to match width unless it's 0.
+func ParseBoard(s string, width int) (Board, error) {
+	if strings.Contains(s, "||") {
+		board, err := parseGrid(s)
+		if err == nil && width > 0 && int(board.Width) != width {
+			err = fmt.Errorf("Board width expected to be %d, got %d", width, board.Width)
+		}
+		return board, err
+	}
+	parser := markupParser{[]rune(s), 0}
+	slots := make([]BoardSpace, 0, len(parser.runes))
+	for !parser.done() {
+		space, err := parser.parseSpace()
+		if err != nil {
+			return Board{}, err
+		}
+		slots = append(slots, space)
+	}
+	if width <= 0 || len(slots) == 0 || len(slots) % width != 0 {
+		return Board{}, fmt.Errorf("%d spaces can't fill a board of width %d", len(slots), width)
+	}
+	// Keeps both dimensions within a uint8 too.
+	if len(slots) > MAX_BOARD_SPACES {
+		return Board{}, fmt.Errorf("Boards can't have more than %d spaces", MAX_BOARD_SPACES)
+	}
+	return Board{slots, uint8(len(slots) / width), uint8(width), 3, nil, 0, false}, nil
+}
+
+// Writes the orb in markup. Attributes hidden by blinds are still written.
+func (self Orb) Markup() string {
+	result := ""
+	if self.State & LOCKED != 0 {
+		result += "&"
+	}
+	if self.State & UNMATCHABLE != 0 {
+		result += "~"
+	}
+	if self.State & STICKY_BLIND != 0 {
+		result += "!"
+	} else if self.State & BLIND != 0 {
+		result += "?"
+	}
+	if self.Attribute == EMPTY {
+		result += "."
+	} else {
+		result += AttributeToLetter[self.Attribute]
+	}
+	if self.State & ENHANCED != 0 {
+		result += "+"
+	}
+	return result
+}
+
+func (self BoardSpace) Markup() string {
+	orb := self.Orb.Markup()
+	switch {
+	case self.State & CLOUD != 0:
+		if self.Orb.Attribute == UNKNOWN && self.Orb.State == 0 {
+			return "{%}"
+		}
+		return "{" + orb + "}"
+	case self.State & TAPE != 0:
+		return "<" + orb + ">"
+	case self.State & SPINNER_1S != 0:
+		return "[" + orb + "]"
+	case self.State & SPINNER_2S != 0:
+		return "(" + orb + ")"
+	}
+	return orb
+}
+
+// Writes the board in markup which ParseBoard reads back to the same board.
+// Only one space state is kept per space.
+func (self Board) Markup() string {
+	result := ""
+	for _, slot := range self.Slots {
+		result += slot.Markup()
+	}
+	return result
+}
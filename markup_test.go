@@ -0,0 +1,155 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBoard_States(t *testing.T) {
+	board, err := ParseBoard("&R+<G>{%}?!D~H[L](B).o", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []BoardSpace{
+		BoardSpace{Orb{FIRE, LOCKED | ENHANCED}, 0},
+		BoardSpace{Orb{WOOD, 0}, TAPE},
+		BoardSpace{Orb{UNKNOWN, 0}, CLOUD},
+		BoardSpace{Orb{UNKNOWN, BLIND}, 0},
+		BoardSpace{Orb{DARK, STICKY_BLIND}, 0},
+		BoardSpace{Orb{HEART, UNMATCHABLE}, 0},
+		BoardSpace{Orb{LIGHT, 0}, SPINNER_1S},
+		BoardSpace{Orb{WATER, 0}, SPINNER_2S},
+		BoardSpace{Orb{EMPTY, 0}, 0},
+		BoardSpace{Orb{BOMB, 0}, 0},
+	}
+	if board.Height != 2 || board.Width != 5 {
+		t.Fatalf("Expected a 5x2 board, got %dx%d", board.Width, board.Height)
+	}
+	for i, space := range expected {
+		if board.Slots[i] != space {
+			t.Errorf("Space %d: expected %v, got %v", i, space, board.Slots[i])
+		}
+	}
+}
+
+func TestParseBoard_RoundTripsString(t *testing.T) {
+	board, err := ParseBoard("&R+<G>{%}?_H+.&D[L](B)RGBLDHJPMo&G.D+RRRRGGGG", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseBoard(board.String(), 0)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, board)
+	}
+	if parsed.String() != board.String() {
+		t.Errorf("Expected\n%s\ngot\n%s", board, parsed)
+	}
+	for i := range board.Slots {
+		if board.Slots[i] != parsed.Slots[i] {
+			t.Errorf("Space %d: expected %v, got %v", i, board.Slots[i], parsed.Slots[i])
+		}
+	}
+}
+
+// Spaces which don't fit in three characters switch String() to markup
+// spaces, which still read back to the same board.
+func TestParseBoard_RoundTripsStringWithHiddenStates(t *testing.T) {
+	board, err := ParseBoard("{R}{&~B+}<?G>[&L+](!D)?H{%}RGBLDHJPMo&G.D+RRRRGGGGBB", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseBoard(board.String(), 6)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, board)
+	}
+	if parsed.Height != board.Height || parsed.Width != board.Width {
+		t.Fatalf("Expected a %dx%d board, got %dx%d", board.Width, board.Height, parsed.Width, parsed.Height)
+	}
+	for i := range board.Slots {
+		if board.Slots[i] != parsed.Slots[i] {
+			t.Errorf("Space %d: expected %v, got %v", i, board.Slots[i], parsed.Slots[i])
+		}
+	}
+}
+
+func TestParseBoard_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		markup string
+	}{
+		{"Unknown letter", "RRRRRX"},
+		{"Unclosed tape", "RRRRR<G"},
+		{"Mismatched close", "RRRRR<G}"},
+		{"Cloud percent outside cloud", "RRRRR%"},
+		{"Wrong size", "RRRR"},
+		{"Bad grid space", "||RRR||"},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseBoard(test.markup, 6); err == nil {
+			t.Errorf("%s: expected an error for \"%s\"", test.name, test.markup)
+		}
+	}
+
+	// Too many spaces for a uint8 position, in markup and in a grid.
+	if board, err := ParseBoard(strings.Repeat("RRRBBB", 50), 300); err == nil {
+		t.Errorf("Expected 300 spaces to be rejected, got a %dx%d board", board.Width, board.Height)
+	}
+	if board, err := ParseBoard(strings.Repeat("|| R ||\n", 256), 1); err == nil {
+		t.Errorf("Expected 256 rows to be rejected, got a %dx%d board", board.Width, board.Height)
+	}
+	if _, err := ParseBoard(strings.Repeat("|| R ||\n", 255), 1); err != nil {
+		t.Errorf("Expected 255 rows to be read, got %s", err)
+	}
+	// A grid carries its own width, which has to match.
+	five_wide := strings.Repeat("|| R  B  G  L  D ||\n", 6)
+	if board, err := ParseBoard(five_wide, 6); err == nil {
+		t.Errorf("Expected a 5 wide grid to be rejected for width 6, got width %d", board.Width)
+	}
+	if _, err := ParseBoard(five_wide, 5); err != nil {
+		t.Errorf("Expected a 5 wide grid to be read for width 5, got %s", err)
+	}
+}
+
+func TestBoardMarkup_RoundTripsParseBoard(t *testing.T) {
+	markup := "&R+<G>{%}?.H+.!D~H[L](&B+)RGBLDHJPMo&G.D+{R}_RRGGG"
+	board, err := ParseBoard(markup, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.Markup() != markup {
+		t.Errorf("Expected %s, got %s", markup, board.Markup())
+	}
+}
+
+func TestBoardMarkup_RoundTripsEveryToken(t *testing.T) {
+	tokens := []string{
+		"R", "B", "G", "L", "D", "H", "J", "P", "M", "o", ".", "_",
+		"&R", "R+", "&R+", "?R", "?_", "?.", "!D", "!.", "~H", "&~R+", "&~!G+",
+		"<R>", "<R+>", "<&R>", "<?B>", "<.>",
+		"{R}", "{?R}", "{&~R+}", "{%}", "{.}",
+		"[L]", "[&L+]", "[!D]", "(B)", "(&B+)", "(~H)",
+	}
+	// A lone ? is written as ?_, so it can't take the attribute of the next
+	// space.
+	if lone, _ := ParseSpace("?"); lone.Markup() != "?_" {
+		t.Errorf("Expected a lone ? to be written as ?_, got %s", lone.Markup())
+	}
+	for _, token := range tokens {
+		space, err := ParseSpace(token)
+		if err != nil {
+			t.Errorf("%s: %s", token, err)
+			continue
+		}
+		if space.Markup() != token {
+			t.Errorf("Expected %s to be written back, got %s", token, space.Markup())
+		}
+		reparsed, err := ParseSpace(space.Markup())
+		if err != nil || reparsed != space {
+			t.Errorf("%s: expected %v, got %v", token, space, reparsed)
+		}
+	}
+}
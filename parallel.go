@@ -0,0 +1,217 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"runtime"
+	"sync"
+)
+
+// States each worker checks per round of ParallelAStarSolve.
+const PARALLEL_BATCH = 32
+
+// Most workers ParallelAStarSolve splits a search between. Each worker has an
+// outbox for every other, so memory grows with the square of the workers.
+const MAX_SEARCH_WORKERS = 64
+
+type parallelWorker struct {
+	queue StatePriorityQueue
+	// Children found this round, by the worker which owns them.
+	outboxes [][]*AStarState
+	best AStarState
+	checked int
+	skipped int
+}
+
+// Index of the worker which owns the state. Only the low bits of the hash
+// RejectionFn keys the TranspositionTable by are used, so every state sharing
+// an entry has the same owner and only one worker ever reads or writes it.
+func parallelOwner(state AStarState, requirements SolveRequirement, seed int64, workers int) int {
+	// Mixed so the seed changes every owner.
+	hash := state.TimedHash(requirements.MoveTimer, requirements.PriorDirections)
+	mixed := mixBits(hash & (1 << TRANSPOSITION_MIN_BITS - 1) ^ uint64(seed))
+	return int(mixed % uint64(workers))
+}
+
+// Like AStarSolve, but splits the frontier between workers by each state's
+// hash. The search runs in rounds: each worker checks and expands its best
+// states, then every child is rejected or queued by the worker which owns it.
+// Since rounds always check and hand off states in the same order, the result
+// only depends on the seed and the number of workers, from 1 to
+// MAX_SEARCH_WORKERS. At most GOMAXPROCS workers run at once, which only
+// changes how long the search takes.
+//
+// Workers share the best state of each round. When UpperBound is set, children
+// which can't beat it are dropped before they are queued.
+//
+// Unlike AStarSolve, each rejected child is skipped on its own, and the goal
+// is checked once per round. ScoreState, UpperBound and RejectionFn are called
+// from several goroutines, so they must be safe for concurrent use.
+func ParallelAStarSolve(ctx context.Context, board Board, requirements SolveRequirement,
+                        workers int, seed int64) SolveResult {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > MAX_SEARCH_WORKERS {
+		workers = MAX_SEARCH_WORKERS
+	}
+	threads := runtime.GOMAXPROCS(0)
+	if threads > workers {
+		threads = workers
+	}
+	board.Rehash()
+	best_state := AStarState{score: -100000}
+	pool := make([]*parallelWorker, workers)
+	for i := range pool {
+		pool[i] = &parallelWorker{
+			queue: MakePriorityQueue(1 << 10),
+			outboxes: make([][]*AStarState, workers),
+			best: best_state,
+		}
+	}
+
+	moves := []Direction{RIGHT, DOWN, LEFT, UP}
+	if requirements.AllowDiagonals {
+		moves = []Direction{RIGHT, DOWN_RIGHT, DOWN, DOWN_LEFT, LEFT, UP_LEFT, UP, UP_RIGHT}
+	}
+	var starting_positions []Pair = requirements.StartingPositions
+	if len(starting_positions) == 0 {
+		for y := uint8(0); y < board.Height; y++ {
+			for x := uint8(0); x < board.Width; x++ {
+				starting_positions = append(starting_positions, Pair{y, x})
+			}
+		}
+	}
+	for _, starting_pos := range starting_positions {
+		for _, move := range moves {
+			board, err := board.Swap(starting_pos, move)
+			if err != nil {
+				continue
+			}
+			if requirements.MoveTimer.Enabled() {
+				start, end := requirements.stepSeconds([]Direction{move})
+				board.TickSpinners(start, end, starting_pos.Swap(move))
+			}
+			new_state := AStarState{
+				board,
+				starting_pos,
+				starting_pos.Swap(move),
+				[]Direction{move},
+				0,
+			}
+			if !requirements.RejectionFn(new_state) {
+				owner := pool[parallelOwner(new_state, requirements, seed, workers)]
+				heap.Push(&owner.queue, &new_state)
+			}
+		}
+	}
+
+	// Runs the function on every worker, with each thread taking every
+	// threads-th worker.
+	each_worker := func(fn func(index int, worker *parallelWorker)) {
+		wait_group := sync.WaitGroup{}
+		for thread := 0; thread < threads; thread++ {
+			wait_group.Add(1)
+			go func(thread int) {
+				defer wait_group.Done()
+				for index := thread; index < len(pool); index += threads {
+					fn(index, pool[index])
+				}
+			}(thread)
+		}
+		wait_group.Wait()
+	}
+
+	reason := GOAL_MET
+	for !requirements.FinishedFn(best_state) {
+		if stop_reason, stopped := contextStopReason(ctx); stopped {
+			reason = stop_reason
+			break
+		}
+		queued := 0
+		for _, worker := range pool {
+			queued += worker.queue.Len()
+		}
+		if queued == 0 {
+			reason = EXHAUSTED
+			break
+		}
+
+		// Check and expand the best states of each worker.
+		round_best := best_state
+		each_worker(func(index int, worker *parallelWorker) {
+			worker.best = round_best
+			for i := 0; i < PARALLEL_BATCH; i++ {
+				state_ptr := popState(&worker.queue)
+				if state_ptr == nil {
+					break
+				}
+				current_state := *state_ptr
+				requirements.ScoreState(current_state)
+				if current_state.score > worker.best.score &&
+				   (!requirements.Restriction.Enabled() ||
+				    !requirements.Restriction.IsViolated(current_state.board.GetAllCombos())) {
+					worker.best = current_state
+				}
+				for _, next_state := range current_state.NextStates(requirements) {
+					next_state := next_state
+					next_state.score = requirements.ScoreState(next_state)
+					owner := parallelOwner(next_state, requirements, seed, workers)
+					worker.outboxes[owner] = append(worker.outboxes[owner], &next_state)
+				}
+				worker.checked++
+				if requirements.FinishedFn(worker.best) {
+					break
+				}
+			}
+		})
+
+		improved := false
+		for _, worker := range pool {
+			if worker.best.score > best_state.score {
+				best_state = worker.best
+				improved = true
+			}
+		}
+		if improved && requirements.OnImproved != nil {
+			requirements.OnImproved(Moves{best_state.starting_pos, best_state.moves}, best_state.score)
+		}
+
+		// Hand each child to its owner, in the order of the workers which found
+		// them.
+		shared_best := best_state.score
+		each_worker(func(index int, worker *parallelWorker) {
+			for _, sender := range pool {
+				for _, next_state := range sender.outboxes[index] {
+					if (requirements.UpperBound != nil && requirements.UpperBound(*next_state) <= shared_best) ||
+					   requirements.RejectionFn(*next_state) {
+						worker.skipped++
+						continue
+					}
+					heap.Push(&worker.queue, next_state)
+				}
+			}
+		})
+		for _, worker := range pool {
+			for i := range worker.outboxes {
+				worker.outboxes[i] = worker.outboxes[i][:0]
+			}
+		}
+	}
+
+	checked, skipped := 0, 0
+	for _, worker := range pool {
+		checked += worker.checked
+		skipped += worker.skipped
+	}
+	result_board := best_state.board
+	if best_state.moves == nil {
+		result_board = board
+	}
+	return SolveResult{
+		Moves{best_state.starting_pos, best_state.moves},
+		result_board,
+		SolveStats{checked, skipped, best_state.score},
+		reason,
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestParallelAStarSolve_Deterministic(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		first := ParallelAStarSolve(context.Background(), swng_board, makeTestRequirement(8, 50), workers, 7)
+		second := ParallelAStarSolve(context.Background(), swng_board, makeTestRequirement(8, 50), workers, 7)
+		if first.Moves.String() != second.Moves.String() || first.Stats != second.Stats {
+			t.Errorf("%d workers: expected the same result, got %s %v and %s %v", workers,
+				first.Moves, first.Stats, second.Moves, second.Stats)
+		}
+		if first.Reason != GOAL_MET {
+			t.Errorf("%d workers: expected %s, got %s", workers, GOAL_MET, first.Reason)
+		}
+		moved, err := swng_board.ApplyMoves(first.Moves, MoveTimer{})
+		if err != nil || moved.SimpleString() != first.Board.SimpleString() {
+			t.Errorf("%d workers: expected the board after %s", workers, first.Moves)
+		}
+		if combos := len(first.Board.GetAllCombos()); combos < 8 {
+			t.Errorf("%d workers: expected at least 8 combos, got %d", workers, combos)
+		}
+	}
+}
+
+func TestParallelAStarSolve_StopReasons(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := ParallelAStarSolve(cancelled, swng_board, makeTestRequirement(20, 50), 2, 0); result.Reason != CANCELLED {
+		t.Errorf("Expected %s, got %s", CANCELLED, result.Reason)
+	}
+	if result := ParallelAStarSolve(context.Background(), swng_board, makeTestRequirement(20, 1), 2, 0); result.Reason != EXHAUSTED {
+		t.Errorf("Expected %s, got %s", EXHAUSTED, result.Reason)
+	}
+}
+
+func TestParallelAStarSolve_WorkersNotLimitedByCPUs(t *testing.T) {
+	previous := runtime.GOMAXPROCS(1)
+	one_cpu := ParallelAStarSolve(context.Background(), swng_board, makeTestRequirement(8, 50), 4, 7)
+	runtime.GOMAXPROCS(4)
+	four_cpus := ParallelAStarSolve(context.Background(), swng_board, makeTestRequirement(8, 50), 4, 7)
+	runtime.GOMAXPROCS(previous)
+
+	if one_cpu.Moves.String() != four_cpus.Moves.String() || one_cpu.Stats != four_cpus.Stats {
+		t.Errorf("Expected 4 workers to search the same on any machine, got %s %v and %s %v",
+			one_cpu.Moves, one_cpu.Stats, four_cpus.Moves, four_cpus.Stats)
+	}
+}
+
+func TestParallelAStarSolve_PrunesAgainstSharedBest(t *testing.T) {
+	pruned := ParallelAStarSolve(context.Background(), swng_board, makeTestRequirement(20, 4), 2, 0)
+	unbounded_requirement := makeTestRequirement(20, 4)
+	unbounded_requirement.UpperBound = nil
+	unbounded := ParallelAStarSolve(context.Background(), swng_board, unbounded_requirement, 2, 0)
+
+	if pruned.Reason != EXHAUSTED || unbounded.Reason != EXHAUSTED {
+		t.Fatalf("Expected both searches to run out, got %s and %s", pruned.Reason, unbounded.Reason)
+	}
+	// Nothing dropped could have beaten the best, so both find the same score.
+	if pruned.Stats.Score != unbounded.Stats.Score {
+		t.Errorf("Expected the same best score, got %d and %d", pruned.Stats.Score, unbounded.Stats.Score)
+	}
+	if pruned.Stats.Checked >= unbounded.Stats.Checked {
+		t.Errorf("Expected pruning to check fewer states, got %d and %d",
+			pruned.Stats.Checked, unbounded.Stats.Checked)
+	}
+}
+
+func benchmarkParallelSolve(b *testing.B, workers int) {
+	for i := 0; i < b.N; i++ {
+		ParallelAStarSolve(context.Background(), swng_board, makeTestRequirement(10, 50), workers, 0)
+	}
+}
+
+func BenchmarkAStarSolve_Swng(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		AStarSolve(context.Background(), swng_board, makeTestRequirement(10, 50))
+	}
+}
+
+func BenchmarkParallelAStarSolve_Swng1(b *testing.B) {
+	benchmarkParallelSolve(b, 1)
+}
+
+func BenchmarkParallelAStarSolve_Swng2(b *testing.B) {
+	benchmarkParallelSolve(b, 2)
+}
+
+func BenchmarkParallelAStarSolve_Swng4(b *testing.B) {
+	benchmarkParallelSolve(b, 4)
+}
+
+func BenchmarkParallelAStarSolve_SwngAll(b *testing.B) {
+	benchmarkParallelSolve(b, runtime.GOMAXPROCS(0))
+}
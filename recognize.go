@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // Registers JPEG screenshots with image.Decode.
+	_ "image/png" // Registers PNG screenshots with image.Decode.
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Cells under this confidence are reported by LowConfidence by default.
+const LOW_CONFIDENCE = 0.25
+
+// Board layouts found in game, as {width, height}.
+var ScreenshotLayouts [][2]int = [][2]int{{5, 4}, {6, 5}, {7, 6}}
+
+// The average color of each attribute's orb. Screenshots from different
+// devices and themes need different profiles.
+type ColorProfile map[OrbAttribute]color.RGBA
+
+var DefaultColorProfile ColorProfile = ColorProfile{
+	FIRE: color.RGBA{233, 84, 60, 255},
+	WATER: color.RGBA{68, 148, 236, 255},
+	WOOD: color.RGBA{72, 196, 92, 255},
+	LIGHT: color.RGBA{246, 226, 92, 255},
+	DARK: color.RGBA{172, 84, 200, 255},
+	HEART: color.RGBA{244, 132, 196, 255},
+	JAMMER: color.RGBA{196, 196, 204, 255},
+	POISON: color.RGBA{124, 64, 148, 255},
+	MORTAL_POISON: color.RGBA{60, 28, 84, 255},
+	BOMB: color.RGBA{60, 60, 60, 255},
+}
+
+// Reads a profile with one attribute per line as "<letter> <red> <green> <blue>".
+// Blank lines and lines starting with # are ignored.
+func ParseColorProfile(reader io.Reader) (ColorProfile, error) {
+	profile := ColorProfile{}
+	scanner := bufio.NewScanner(reader)
+	line_number := 0
+	for scanner.Scan() {
+		line_number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("Line %d: expected \"<letter> <red> <green> <blue>\"", line_number)
+		}
+		attribute, exists := LetterToAttribute[fields[0]]
+		if !exists || attribute == EMPTY || attribute == UNKNOWN {
+			return nil, fmt.Errorf("Line %d: unknown attribute \"%s\"", line_number, fields[0])
+		}
+		values := [3]uint8{}
+		for i := 0; i < 3; i++ {
+			value, err := strconv.ParseUint(fields[i + 1], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("Line %d: invalid color value \"%s\"", line_number, fields[i + 1])
+			}
+			values[i] = uint8(value)
+		}
+		profile[attribute] = color.RGBA{values[0], values[1], values[2], 255}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(profile) < 2 {
+		return nil, fmt.Errorf("A profile needs at least 2 attributes, got %d", len(profile))
+	}
+	return profile, nil
+}
+
+func LoadColorProfile(path string) (ColorProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseColorProfile(file)
+}
+
+func colorDistance(a color.RGBA, b color.RGBA) float64 {
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	return math.Sqrt(dr * dr + dg * dg + db * db)
+}
+
+// Finds the closest attribute to the color. Confidence is 1 when the color
+// matches exactly and 0 when it is as close to the runner up.
+func (self ColorProfile) Classify(sample color.RGBA) (OrbAttribute, float64) {
+	best, second := math.Inf(1), math.Inf(1)
+	best_attribute := EMPTY
+	// Iterate in a fixed order so ties are broken the same way every time.
+	for _, attribute := range ALL_ATTRIBUTES {
+		reference, exists := self[attribute]
+		if !exists {
+			continue
+		}
+		distance := colorDistance(sample, reference)
+		if distance < best {
+			best, second = distance, best
+			best_attribute = attribute
+		} else if distance < second {
+			second = distance
+		}
+	}
+	if best + second == 0 || math.IsInf(second, 1) {
+		return best_attribute, 1
+	}
+	return best_attribute, (second - best) / (second + best)
+}
+
+// Average color of the rectangle.
+func averageColor(img image.Image, rect image.Rectangle) color.RGBA {
+	var r, g, b, count uint64
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			r += uint64(cr >> 8)
+			g += uint64(cg >> 8)
+			b += uint64(cb >> 8)
+			count++
+		}
+	}
+	if count == 0 {
+		return color.RGBA{0, 0, 0, 255}
+	}
+	return color.RGBA{uint8(r / count), uint8(g / count), uint8(b / count), 255}
+}
+
+type RecognizedCell struct {
+	Attribute OrbAttribute
+	Confidence float64
+}
+
+type Recognition struct {
+	Board Board
+	// Cells in the same order as Board.Slots.
+	Cells []RecognizedCell
+}
+
+func (self Recognition) Confidence() float64 {
+	if len(self.Cells) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, cell := range self.Cells {
+		total += cell.Confidence
+	}
+	return total / float64(len(self.Cells))
+}
+
+// Every cell recognized with less than the threshold confidence.
+func (self Recognition) LowConfidence(threshold float64) []Pair {
+	placements := make([]Pair, 0)
+	for i, cell := range self.Cells {
+		if cell.Confidence < threshold {
+			placements = append(placements, self.Board.ToPair(uint8(i)))
+		}
+	}
+	return placements
+}
+
+// Reads the orbs of a width x height board. The board is expected to fill the
+// full width of the screenshot and sit at its bottom, as it does in game.
+func RecognizeLayout(img image.Image, profile ColorProfile, width int, height int) (Recognition, error) {
+	bounds := img.Bounds()
+	cell_size := bounds.Dx() / width
+	if cell_size < 4 || cell_size * height > bounds.Dy() {
+		return Recognition{}, fmt.Errorf("A %dx%d board does not fit in a %dx%d image",
+			width, height, bounds.Dx(), bounds.Dy())
+	}
+	top := bounds.Max.Y - cell_size * height
+	// Only sample the middle of each cell, away from the edges of the orb.
+	margin := cell_size * 3 / 10
+
+	slots := make([]BoardSpace, width * height)
+	cells := make([]RecognizedCell, width * height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			min := image.Point{bounds.Min.X + x * cell_size + margin, top + y * cell_size + margin}
+			max := image.Point{bounds.Min.X + (x + 1) * cell_size - margin, top + (y + 1) * cell_size - margin}
+			attribute, confidence := profile.Classify(averageColor(img, image.Rectangle{min, max}))
+			slots[y * width + x].Orb.Attribute = attribute
+			cells[y * width + x] = RecognizedCell{attribute, confidence}
+		}
+	}
+	board := Board{slots, uint8(height), uint8(width), 3, nil, 0, false}
+	return Recognition{board, cells}, nil
+}
+
+// Reads the board from a screenshot, picking whichever layout it is most
+// confident in.
+func RecognizeBoard(img image.Image, profile ColorProfile) (Recognition, error) {
+	best := Recognition{}
+	found := false
+	for _, layout := range ScreenshotLayouts {
+		recognition, err := RecognizeLayout(img, profile, layout[0], layout[1])
+		if err != nil {
+			continue
+		}
+		if !found || recognition.Confidence() > best.Confidence() {
+			best = recognition
+			found = true
+		}
+	}
+	if !found {
+		return best, fmt.Errorf("No board layout fits in the screenshot")
+	}
+	return best, nil
+}
+
+// Reads a PNG or JPEG screenshot from disk.
+func RecognizeScreenshot(path string, profile ColorProfile) (Recognition, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Recognition{}, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return Recognition{}, err
+	}
+	return RecognizeBoard(img, profile)
+}
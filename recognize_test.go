@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecognizeScreenshot_Layouts(t *testing.T) {
+	profile, err := LoadColorProfile("testdata/profile.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		width uint8
+		height uint8
+		expected string
+	}{
+		{"testdata/board_5x4.jpg", 5, 4, "RBGLDHJPRBGLDHRBGLDH"},
+		{"testdata/board_6x5.png", 6, 5, "RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG"},
+		{"testdata/board_7x6.png", 7, 6, "RBGLDHJPMoRBGLDHRBGLDHRBGLDHRBGLDHRBGLDHRB"},
+	}
+
+	for _, test := range tests {
+		recognition, err := RecognizeScreenshot(test.path, profile)
+		if err != nil {
+			t.Errorf("%s: %s", test.path, err)
+			continue
+		}
+		board := recognition.Board
+		if board.Width != test.width || board.Height != test.height {
+			t.Errorf("%s: expected a %dx%d board, got %dx%d", test.path,
+				test.width, test.height, board.Width, board.Height)
+			continue
+		}
+		if board.SimpleString() != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.path, test.expected, board.SimpleString())
+		}
+		if low := recognition.LowConfidence(LOW_CONFIDENCE); len(low) != 0 {
+			t.Errorf("%s: unexpected low confidence cells %v", test.path, low)
+		}
+	}
+}
+
+func TestRecognizeScreenshot_FlagsUnclearCell(t *testing.T) {
+	recognition, err := RecognizeScreenshot("testdata/board_6x5_unclear.png", DefaultColorProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	low := recognition.LowConfidence(LOW_CONFIDENCE)
+	if len(low) != 1 || low[0] != (Pair{1, 0}) {
+		t.Errorf("Expected only (1,0) to be unclear, got %v", low)
+	}
+}
+
+func TestParseColorProfile_Errors(t *testing.T) {
+	tests := []string{
+		"R 1 2",
+		"X 1 2 3\nB 1 2 3",
+		"R 1 2 300\nB 1 2 3",
+		"R 1 2 3",
+	}
+
+	for _, profile := range tests {
+		if _, err := ParseColorProfile(strings.NewReader(profile)); err == nil {
+			t.Errorf("Expected an error for %q", profile)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Background color of each attribute from the 256 color ANSI palette.
+var AttributeToANSI map[OrbAttribute]int = map[OrbAttribute]int{
+	FIRE: 196,
+	WATER: 33,
+	WOOD: 40,
+	LIGHT: 226,
+	DARK: 129,
+	HEART: 213,
+	JAMMER: 250,
+	POISON: 97,
+	MORTAL_POISON: 54,
+	BOMB: 240,
+	UNKNOWN: 244,
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	// Dark gray used for empty spaces and orbs that aren't highlighted.
+	ansiDim = 236
+	// Black text on top of orb colors.
+	ansiText = 16
+)
+
+// Gap between boards printed side by side.
+const ANSI_BOARD_GAP = "  "
+
+// Whether the file is an interactive terminal rather than a pipe or file.
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode() & os.ModeCharDevice != 0
+}
+
+// Renders boards for a terminal. Without Color, renders plain text in the same
+// layout.
+type ANSIRenderer struct {
+	Color bool
+}
+
+// Creates a renderer which only uses color when the file is a terminal.
+func MakeANSIRenderer(file *os.File) ANSIRenderer {
+	return ANSIRenderer{IsTerminal(file)}
+}
+
+// Draws a three character space. Text replaces the orb's letters when set.
+func (self ANSIRenderer) space(space BoardSpace, text string, highlighted bool) string {
+	if text == "" {
+		text = space.String()
+	}
+	if !self.Color {
+		return text
+	}
+	background := ansiDim
+	if code, exists := AttributeToANSI[space.Orb.Attribute]; exists && highlighted {
+		background = code
+	}
+	return fmt.Sprintf("\x1b[48;5;%dm\x1b[38;5;%dm%s%s", background, ansiText, text, ansiReset)
+}
+
+// Draws the board line by line. Text and highlighted are indexed by position,
+// either may be nil.
+func (self ANSIRenderer) boardLines(board Board, text []string, highlighted []bool) []string {
+	border := "++" + strings.Repeat("-", 3 * int(board.Width)) + "++"
+	lines := []string{border}
+	for y := uint8(0); y < board.Height; y++ {
+		line := "||"
+		for x := uint8(0); x < board.Width; x++ {
+			pos := Pair{y, x}.ToPos(board)
+			cell_text := ""
+			if text != nil {
+				cell_text = text[pos]
+			}
+			line += self.space(board.Slots[pos], cell_text, highlighted == nil || highlighted[pos])
+		}
+		lines = append(lines, line + "||")
+	}
+	return append(lines, border)
+}
+
+func (self ANSIRenderer) Board(board Board) string {
+	return strings.Join(self.boardLines(board, nil, nil), "\n") + "\n"
+}
+
+// Draws the board with the order the path visits each space. The start is
+// marked S, the end E, and other spaces show the last step to reach them. A
+// path which leaves the board is drawn up to its last space on the board, and
+// nothing is drawn for a start off the board.
+func (self ANSIRenderer) Path(board Board, moves Moves) string {
+	text := make([]string, len(board.Slots))
+	on_board := func(placement Pair) bool {
+		return placement.Y < board.Height && placement.X < board.Width
+	}
+	if on_board(moves.StartingPosition) {
+		placement := moves.StartingPosition
+		end := placement
+		for i, direction := range moves.Directions {
+			placement = placement.Swap(direction)
+			if !on_board(placement) {
+				break
+			}
+			if i + 1 < 100 {
+				text[placement.ToPos(board)] = fmt.Sprintf("%2d ", i + 1)
+			} else {
+				text[placement.ToPos(board)] = "** "
+			}
+			end = placement
+		}
+		text[moves.StartingPosition.ToPos(board)] = " S "
+		text[end.ToPos(board)] = " E "
+		if len(moves.Directions) == 0 {
+			text[end.ToPos(board)] = "S/E"
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == "" {
+			text[i] = "   "
+		}
+	}
+	return strings.Join(self.boardLines(board, text, nil), "\n") + "\n"
+}
+
+// Draws every cascade step side by side, highlighting the orbs each step
+// clears.
+func (self ANSIRenderer) CascadeSteps(steps []CascadeStep) string {
+	if len(steps) == 0 {
+		return "No combos.\n"
+	}
+	columns := make([][]string, len(steps))
+	for i, step := range steps {
+		highlighted := make([]bool, len(step.Board.Slots))
+		for _, combo := range step.Combos {
+			for _, placement := range combo.Positions {
+				highlighted[placement.ToPos(step.Board)] = true
+			}
+		}
+		board_width := 3 * int(step.Board.Width) + 4
+		header := fmt.Sprintf("Step %d: %d combos", i + 1, len(step.Combos))
+		if len(header) < board_width {
+			header += strings.Repeat(" ", board_width - len(header))
+		}
+		columns[i] = append([]string{header[:board_width]}, self.boardLines(step.Board, nil, highlighted)...)
+	}
+
+	lines := make([]string, len(columns[0]))
+	for i := range lines {
+		parts := make([]string, 0, len(columns))
+		for _, column := range columns {
+			if i < len(column) {
+				parts = append(parts, column[i])
+			}
+		}
+		lines[i] = strings.Join(parts, ANSI_BOARD_GAP)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestANSIRenderer_PlainMatchesString(t *testing.T) {
+	renderer := ANSIRenderer{false}
+
+	if renderer.Board(swng_board) != swng_board.String() {
+		t.Errorf("Expected\n%s\ngot\n%s", swng_board, renderer.Board(swng_board))
+	}
+	if strings.Contains(renderer.CascadeSteps(swng_board.GetCascadeSteps()), "\x1b[") {
+		t.Error("Plain rendering should not contain escape codes.")
+	}
+}
+
+func TestANSIRenderer_ColorsByAttribute(t *testing.T) {
+	board, _ := ParseBoard("RB.", 3)
+	rendered := ANSIRenderer{true}.Board(board)
+
+	for _, code := range []string{"\x1b[48;5;196m", "\x1b[48;5;33m", "\x1b[48;5;236m"} {
+		if !strings.Contains(rendered, code) {
+			t.Errorf("Expected %q in %q", code, rendered)
+		}
+	}
+}
+
+func TestANSIRenderer_PathOrder(t *testing.T) {
+	moves := Moves{Pair{0, 0}, []Direction{RIGHT, RIGHT, DOWN}}
+	lines := strings.Split(ANSIRenderer{false}.Path(swng_board, moves), "\n")
+
+	if !strings.HasPrefix(lines[1], "|| S  1  2 ") {
+		t.Errorf("Unexpected first row: %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "||       E ") {
+		t.Errorf("Unexpected second row: %q", lines[2])
+	}
+}
+
+func TestANSIRenderer_PathOffBoard(t *testing.T) {
+	// Leaves the board after two steps, so the second step is the end.
+	moves := Moves{Pair{0, 0}, []Direction{RIGHT, UP, UP}}
+	lines := strings.Split(ANSIRenderer{false}.Path(swng_board, moves), "\n")
+	if !strings.HasPrefix(lines[1], "|| S  E ") {
+		t.Errorf("Unexpected first row: %q", lines[1])
+	}
+
+	blank := ANSIRenderer{false}.Path(swng_board, Moves{})
+	for _, start := range []Pair{Pair{swng_board.Height, 0}, Pair{0, swng_board.Width}, Pair{255, 255}} {
+		rendered := ANSIRenderer{false}.Path(swng_board, Moves{start, []Direction{LEFT, UP}})
+		if strings.Contains(rendered, " S ") || strings.Contains(rendered, " E ") {
+			t.Errorf("Expected nothing drawn for a start at %v:\n%s", start, rendered)
+		}
+		if len(rendered) != len(blank) {
+			t.Errorf("Expected a start at %v to draw the whole board:\n%s", start, rendered)
+		}
+	}
+}
+
+func TestANSIRenderer_CascadeStepsSideBySide(t *testing.T) {
+	steps := swng_board.GetCascadeSteps()
+	lines := strings.Split(ANSIRenderer{false}.CascadeSteps(steps), "\n")
+
+	board_width := 3 * int(swng_board.Width) + 4
+	expected_width := len(steps) * board_width + (len(steps) - 1) * len(ANSI_BOARD_GAP)
+	if len(lines[1]) != expected_width || !strings.HasPrefix(lines[0], "Step 1:") {
+		t.Errorf("Expected %d steps side by side:\n%s", len(steps), strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+)
+
+// Pixel size of a board space in rendered images.
+const RENDER_CELL_SIZE = 40
+
+// Number of frames a combo takes to fade out.
+const GIF_FADE_FRAMES = 3
+
+// Frame delays in 100ths of a second.
+const (
+	GIF_BOARD_DELAY = 100
+	GIF_MOVE_DELAY = 15
+	GIF_FADE_DELAY = 10
+	GIF_DROP_DELAY = 50
+)
+
+var (
+	renderBackgrounds = [2]color.RGBA{{74, 46, 28, 255}, {98, 62, 38, 255}}
+	gifHeldColor = color.RGBA{255, 255, 255, 255}
+	renderUnknownColor = color.RGBA{128, 128, 128, 255}
+)
+
+// Palette index layout: the two backgrounds, the held marker, then
+// GIF_FADE_FRAMES + 1 shades of each attribute from solid to nearly gone.
+const (
+	gifBackgroundIndex = 0
+	gifHeldIndex = 2
+	gifOrbIndex = 3
+)
+
+func blendColor(from color.RGBA, to color.RGBA, amount float64) color.RGBA {
+	mix := func(a uint8, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b) - float64(a)) * amount)
+	}
+	return color.RGBA{mix(from.R, to.R), mix(from.G, to.G), mix(from.B, to.B), 255}
+}
+
+func orbRenderColor(attribute OrbAttribute) color.RGBA {
+	if orb_color, exists := DefaultColorProfile[attribute]; exists {
+		return orb_color
+	}
+	return renderUnknownColor
+}
+
+func makeGIFPalette() color.Palette {
+	palette := color.Palette{renderBackgrounds[0], renderBackgrounds[1], gifHeldColor}
+	for attribute := EMPTY; attribute <= UNKNOWN; attribute++ {
+		for shade := 0; shade <= GIF_FADE_FRAMES; shade++ {
+			amount := float64(shade) / float64(GIF_FADE_FRAMES + 1)
+			palette = append(palette, blendColor(orbRenderColor(attribute), renderBackgrounds[0], amount))
+		}
+	}
+	return palette
+}
+
+func gifOrbColorIndex(attribute OrbAttribute, shade int) uint8 {
+	return uint8(gifOrbIndex + int(attribute) * (GIF_FADE_FRAMES + 1) + shade)
+}
+
+// Draws one frame. Orbs in fading are drawn at the given shade, and the held
+// placement is ringed when held is true.
+func drawGIFFrame(board Board, palette color.Palette, fading []bool, shade int,
+                  held Pair, is_held bool) *image.Paletted {
+	size := RENDER_CELL_SIZE
+	frame := image.NewPaletted(image.Rect(0, 0, int(board.Width) * size, int(board.Height) * size), palette)
+	radius := float64(size) * 0.42
+	for y := 0; y < int(board.Height); y++ {
+		for x := 0; x < int(board.Width); x++ {
+			pos := y * int(board.Width) + x
+			attribute := board.Slots[pos].Orb.Attribute
+			orb_shade := 0
+			if fading != nil && fading[pos] {
+				orb_shade = shade
+			}
+			ringed := is_held && held == Pair{uint8(y), uint8(x)}
+			for py := 0; py < size; py++ {
+				for px := 0; px < size; px++ {
+					dx := float64(px) + 0.5 - float64(size) / 2
+					dy := float64(py) + 0.5 - float64(size) / 2
+					distance := dx * dx + dy * dy
+					index := uint8(gifBackgroundIndex + (x + y) % 2)
+					if attribute != EMPTY && distance <= radius * radius {
+						index = gifOrbColorIndex(attribute, orb_shade)
+					}
+					if ringed && distance > (radius - 3) * (radius - 3) && distance <= radius * radius {
+						index = gifHeldIndex
+					}
+					frame.SetColorIndex(x * size + px, y * size + py, index)
+				}
+			}
+		}
+	}
+	return frame
+}
+
+// Animates the moves being made on the board, spinning spinners as the timer
+// says, then every cascade fading out and the orbs above dropping into place.
+func RenderSolutionGIF(board Board, moves Moves, timer MoveTimer, writer io.Writer) error {
+	palette := makeGIFPalette()
+	animation := &gif.GIF{}
+	add_frame := func(frame *image.Paletted, delay int) {
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, delay)
+	}
+
+	add_frame(drawGIFFrame(board, palette, nil, 0, moves.StartingPosition, false), GIF_BOARD_DELAY)
+	current_board := board.Clone()
+	placement := moves.StartingPosition
+	add_frame(drawGIFFrame(current_board, palette, nil, 0, placement, true), GIF_MOVE_DELAY)
+	elapsed := 0.0
+	for i, direction := range moves.Directions {
+		new_board, err := current_board.Swap(placement, direction)
+		if err != nil {
+			return err
+		}
+		current_board = new_board
+		placement = placement.Swap(direction)
+		if timer.Enabled() {
+			step := timer.StepSeconds(moves.Directions, i)
+			current_board.TickSpinners(elapsed, elapsed + step, placement)
+			elapsed += step
+		}
+		add_frame(drawGIFFrame(current_board, palette, nil, 0, placement, true), GIF_MOVE_DELAY)
+	}
+	add_frame(drawGIFFrame(current_board, palette, nil, 0, placement, false), GIF_BOARD_DELAY)
+
+	for _, step := range current_board.GetCascadeSteps() {
+		fading := make([]bool, len(step.Board.Slots))
+		after := step.Board.Clone()
+		for _, combo := range step.Combos {
+			for _, combo_placement := range combo.Positions {
+				pos := combo_placement.ToPos(step.Board)
+				fading[pos] = true
+				after.Slots[pos].Orb.Attribute = EMPTY
+			}
+		}
+		for shade := 1; shade <= GIF_FADE_FRAMES; shade++ {
+			add_frame(drawGIFFrame(step.Board, palette, fading, shade, placement, false), GIF_FADE_DELAY)
+		}
+		after.dropOrbs()
+		add_frame(drawGIFFrame(after, palette, nil, 0, placement, false), GIF_DROP_DELAY)
+	}
+	// Linger on the final board before looping.
+	animation.Delay[len(animation.Delay) - 1] = GIF_BOARD_DELAY
+	return gif.EncodeAll(writer, animation)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+)
+
+func TestRenderSolutionGIF_FramePerStep(t *testing.T) {
+	moves := Moves{Pair{4, 5}, []Direction{UP, LEFT}}
+	final_board, _ := swng_board.ApplyMoves(moves, MoveTimer{})
+	cascades := len(final_board.GetCascadeSteps())
+
+	buffer := bytes.Buffer{}
+	if err := RenderSolutionGIF(swng_board, moves, MoveTimer{}, &buffer); err != nil {
+		t.Fatal(err)
+	}
+	animation, err := gif.DecodeAll(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Start, pick up, one per move, release, then fades and a drop per cascade.
+	expected := 3 + len(moves.Directions) + cascades * (GIF_FADE_FRAMES + 1)
+	if len(animation.Image) != expected {
+		t.Errorf("Expected %d frames, got %d", expected, len(animation.Image))
+	}
+	bounds := animation.Image[0].Bounds()
+	if bounds.Dx() != 6 * RENDER_CELL_SIZE || bounds.Dy() != 5 * RENDER_CELL_SIZE {
+		t.Errorf("Unexpected frame size %v", bounds)
+	}
+}
+
+func TestRenderSolutionGIF_InvalidMove(t *testing.T) {
+	moves := Moves{Pair{0, 0}, []Direction{UP}}
+	if err := RenderSolutionGIF(swng_board, moves, MoveTimer{}, &bytes.Buffer{}); err == nil {
+		t.Error("Moving off the board should error.")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// Height of each line of the combo list below the board.
+const SVG_LINE_HEIGHT = 20
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Center of the placement's space in pixels.
+func svgCenter(placement Pair) (int, int) {
+	return int(placement.X) * RENDER_CELL_SIZE + RENDER_CELL_SIZE / 2,
+		int(placement.Y) * RENDER_CELL_SIZE + RENDER_CELL_SIZE / 2
+}
+
+func writeSVGSpace(writer io.Writer, board Board, placement Pair) {
+	space := board.Slots[placement.ToPos(board)]
+	x, y := int(placement.X) * RENDER_CELL_SIZE, int(placement.Y) * RENDER_CELL_SIZE
+	cx, cy := svgCenter(placement)
+	radius := RENDER_CELL_SIZE * 42 / 100
+	name := strings.ToLower(strings.ReplaceAll(space.Orb.Attribute.String(), " ", "-"))
+
+	fmt.Fprintf(writer, "<g class=\"space\" data-y=\"%d\" data-x=\"%d\">\n", placement.Y, placement.X)
+	fmt.Fprintf(writer, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n",
+		x, y, RENDER_CELL_SIZE, RENDER_CELL_SIZE, svgColor(renderBackgrounds[(placement.X + placement.Y) % 2]))
+	if space.Orb.Attribute != EMPTY {
+		fmt.Fprintf(writer, "<circle class=\"orb %s\" cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"%s\"/>\n",
+			name, cx, cy, radius, svgColor(orbRenderColor(space.Orb.Attribute)))
+	}
+	if space.Orb.State & LOCKED != 0 {
+		fmt.Fprintf(writer, "<circle class=\"locked\" cx=\"%d\" cy=\"%d\" r=\"%d\" fill=\"none\" stroke=\"#c0c0c0\" stroke-width=\"3\" stroke-dasharray=\"4 2\"/>\n",
+			cx, cy, radius)
+	}
+	if space.Orb.State & ENHANCED != 0 {
+		fmt.Fprintf(writer, "<text class=\"enhanced\" x=\"%d\" y=\"%d\" font-size=\"14\" font-weight=\"bold\" fill=\"#ffffff\">+</text>\n",
+			x + RENDER_CELL_SIZE - 12, y + 14)
+	}
+	if space.Orb.IsBlinded() {
+		fmt.Fprintf(writer, "<text class=\"blind\" x=\"%d\" y=\"%d\" font-size=\"20\" text-anchor=\"middle\" fill=\"#000000\">?</text>\n",
+			cx, cy + 7)
+	}
+	if space.State & TAPE != 0 {
+		fmt.Fprintf(writer, "<rect class=\"tape\" x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#e8d8a0\" fill-opacity=\"0.7\"/>\n",
+			x, cy - RENDER_CELL_SIZE / 8, RENDER_CELL_SIZE, RENDER_CELL_SIZE / 4)
+	}
+	if space.State & (SPINNER_1S | SPINNER_2S) != 0 {
+		fmt.Fprintf(writer, "<rect class=\"spinner\" x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"none\" stroke=\"#ffffff\" stroke-width=\"2\" stroke-dasharray=\"3 3\"/>\n",
+			x + 1, y + 1, RENDER_CELL_SIZE - 2, RENDER_CELL_SIZE - 2)
+	}
+	if space.State & CLOUD != 0 {
+		fmt.Fprintf(writer, "<rect class=\"cloud\" x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"8\" fill=\"#f0f0f0\" fill-opacity=\"0.85\"/>\n",
+			x + 2, y + 2, RENDER_CELL_SIZE - 4, RENDER_CELL_SIZE - 4)
+	}
+	fmt.Fprintf(writer, "</g>\n")
+}
+
+// Draws the board with the path of the moves on top of it, and lists the
+// combos the moves make below the board. Spinners spin as the timer says,
+// the same as when solving.
+func RenderSolutionSVG(board Board, moves Moves, timer MoveTimer, writer io.Writer) error {
+	final_board, err := board.ApplyMoves(moves, timer)
+	if err != nil {
+		return err
+	}
+	combos := final_board.GetAllCombos()
+
+	width := int(board.Width) * RENDER_CELL_SIZE
+	board_height := int(board.Height) * RENDER_CELL_SIZE
+	height := board_height + SVG_LINE_HEIGHT * (len(combos) + 1) + SVG_LINE_HEIGHT / 2
+
+	buffered := bufio.NewWriter(writer)
+	fmt.Fprintf(buffered, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		width, height, width, height)
+	fmt.Fprintf(buffered, "<defs><marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"5\" refY=\"5\" markerWidth=\"4\" markerHeight=\"4\" orient=\"auto-start-reverse\"><path d=\"M 0 0 L 10 5 L 0 10 z\" fill=\"#ffffff\"/></marker></defs>\n")
+	fmt.Fprintf(buffered, "<g class=\"board\">\n")
+	for y := uint8(0); y < board.Height; y++ {
+		for x := uint8(0); x < board.Width; x++ {
+			writeSVGSpace(buffered, board, Pair{y, x})
+		}
+	}
+	fmt.Fprintf(buffered, "</g>\n")
+
+	// Path from the starting position through every step.
+	points := make([]string, 0, len(moves.Directions) + 1)
+	placement := moves.StartingPosition
+	start_x, start_y := svgCenter(placement)
+	points = append(points, fmt.Sprintf("%d,%d", start_x, start_y))
+	for _, direction := range moves.Directions {
+		placement = placement.Swap(direction)
+		x, y := svgCenter(placement)
+		points = append(points, fmt.Sprintf("%d,%d", x, y))
+	}
+	end_x, end_y := svgCenter(placement)
+	fmt.Fprintf(buffered, "<g class=\"path\">\n")
+	fmt.Fprintf(buffered, "<polyline points=\"%s\" fill=\"none\" stroke=\"#ffffff\" stroke-width=\"4\" stroke-linejoin=\"round\" stroke-opacity=\"0.8\" marker-mid=\"url(#arrow)\" marker-end=\"url(#arrow)\"/>\n",
+		strings.Join(points, " "))
+	fmt.Fprintf(buffered, "<circle class=\"start\" cx=\"%d\" cy=\"%d\" r=\"7\" fill=\"#30d030\" stroke=\"#ffffff\" stroke-width=\"2\"/>\n",
+		start_x, start_y)
+	fmt.Fprintf(buffered, "<rect class=\"end\" x=\"%d\" y=\"%d\" width=\"14\" height=\"14\" fill=\"#d03030\" stroke=\"#ffffff\" stroke-width=\"2\"/>\n",
+		end_x - 7, end_y - 7)
+	fmt.Fprintf(buffered, "</g>\n")
+
+	fmt.Fprintf(buffered, "<g class=\"combos\" font-family=\"monospace\" font-size=\"14\">\n")
+	fmt.Fprintf(buffered, "<text x=\"4\" y=\"%d\">%d combos</text>\n", board_height + SVG_LINE_HEIGHT, len(combos))
+	for i, combo := range combos {
+		fmt.Fprintf(buffered, "<text class=\"combo\" x=\"4\" y=\"%d\" fill=\"%s\">%s</text>\n",
+			board_height + SVG_LINE_HEIGHT * (i + 2), svgColor(blendColor(orbRenderColor(combo.Attribute), color.RGBA{0, 0, 0, 255}, 0.3)),
+			html.EscapeString(combo.String()))
+	}
+	fmt.Fprintf(buffered, "</g>\n")
+	fmt.Fprintf(buffered, "</svg>\n")
+	return buffered.Flush()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenderSolutionSVG_ValidWithPathAndCombos(t *testing.T) {
+	board, _ := ParseBoard("&R+<G>{%}?.H+.&D[L](B)RGBLDHJPMo&G.D+RRRRGGGG", 6)
+	moves := Moves{Pair{4, 0}, []Direction{RIGHT, RIGHT, UP}}
+	final_board, _ := board.ApplyMoves(moves, MoveTimer{})
+	combos := final_board.GetAllCombos()
+
+	buffer := bytes.Buffer{}
+	if err := RenderSolutionSVG(board, moves, MoveTimer{}, &buffer); err != nil {
+		t.Fatal(err)
+	}
+	svg := buffer.String()
+
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid SVG: %s\n%s", err, svg)
+		}
+	}
+	if strings.Count(svg, "class=\"space\"") != 30 {
+		t.Errorf("Expected 30 spaces:\n%s", svg)
+	}
+	if !strings.Contains(svg, "points=\"20,180 60,180 100,180 100,140\"") {
+		t.Errorf("Expected the path through every step:\n%s", svg)
+	}
+	if strings.Count(svg, "class=\"combo\"") != len(combos) {
+		t.Errorf("Expected %d combos listed:\n%s", len(combos), svg)
+	}
+	for _, decoration := range []string{"locked", "enhanced", "blind", "tape", "cloud", "spinner"} {
+		if !strings.Contains(svg, "class=\"" + decoration + "\"") {
+			t.Errorf("Expected a %s decoration:\n%s", decoration, svg)
+		}
+	}
+}
+
+func TestRenderSolutionSVG_SpinsSpinners(t *testing.T) {
+	// L L [G]
+	// R B  H
+	// D H  B
+	board, _ := ParseBoard("LL[G]RBHDHB", 3)
+	// The spinner turns to Light during the last step, making the only combo.
+	moves := Moves{Pair{2, 0}, []Direction{RIGHT, RIGHT, UP}}
+	timer := MoveTimer{0.25, 0.5, 1}
+
+	buffer := bytes.Buffer{}
+	if err := RenderSolutionSVG(board, moves, timer, &buffer); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(buffer.String(), "class=\"combo\"") != 1 {
+		t.Errorf("Expected the spun Light combo to be listed:\n%s", buffer.String())
+	}
+}
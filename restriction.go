@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Flags of a BoardRestriction. Attribute flags limit matching to only those
+// attributes, size flags require every combo to have at least that many orbs.
+type RestrictionFlags uint16
+
+const (
+	RESTRICT_4PLUS RestrictionFlags = 1 << iota
+	RESTRICT_5PLUS
+	RESTRICT_FIRE
+	RESTRICT_WATER
+	RESTRICT_WOOD
+	RESTRICT_LIGHT
+	RESTRICT_DARK
+	RESTRICT_HEART
+	RESTRICT_POISON
+)
+
+const RESTRICT_ATTRIBUTES RestrictionFlags = RESTRICT_FIRE | RESTRICT_WATER | RESTRICT_WOOD |
+	RESTRICT_LIGHT | RESTRICT_DARK | RESTRICT_HEART | RESTRICT_POISON
+
+var AttributeToRestriction map[OrbAttribute]RestrictionFlags = map[OrbAttribute]RestrictionFlags{
+	FIRE: RESTRICT_FIRE,
+	WATER: RESTRICT_WATER,
+	WOOD: RESTRICT_WOOD,
+	LIGHT: RESTRICT_LIGHT,
+	DARK: RESTRICT_DARK,
+	HEART: RESTRICT_HEART,
+	POISON: RESTRICT_POISON,
+}
+
+var NameToRestriction map[string]RestrictionFlags = map[string]RestrictionFlags{
+	"4plus": RESTRICT_4PLUS,
+	"5plus": RESTRICT_5PLUS,
+	"fire": RESTRICT_FIRE,
+	"water": RESTRICT_WATER,
+	"wood": RESTRICT_WOOD,
+	"light": RESTRICT_LIGHT,
+	"dark": RESTRICT_DARK,
+	"heart": RESTRICT_HEART,
+	"poison": RESTRICT_POISON,
+}
+
+var restrictionNames []string = []string{"4plus", "5plus", "fire", "water", "wood", "light", "dark", "heart", "poison"}
+
+// Dungeon restrictions on which combos count. e.g. RESTRICT_5PLUS |
+// RESTRICT_LIGHT only allows Light combos of 5 or more orbs, while
+// {LIGHT: 5} in AttributeMinimums needs 5 Light orbs but only 3 of the others.
+type BoardRestriction struct {
+	Flags RestrictionFlags
+	// Per attribute overrides of the minimum orbs set by the size flags. Shared
+	// between copies, so treat it as read-only.
+	AttributeMinimums map[OrbAttribute]int
+}
+
+func attributeOfRestriction(flag RestrictionFlags) (OrbAttribute, bool) {
+	for attribute, attribute_flag := range AttributeToRestriction {
+		if attribute_flag == flag {
+			return attribute, true
+		}
+	}
+	return EMPTY, false
+}
+
+// Parses a comma separated list of restriction names, e.g. "5plus,light".
+// name=number sets the minimum orbs of a single attribute, e.g. "light=5" for
+// Light needing 5 orbs while the others need 3.
+func ParseRestriction(s string) (BoardRestriction, error) {
+	restriction := BoardRestriction{}
+	if s == "" {
+		return restriction, nil
+	}
+	for _, spec := range strings.Split(s, ",") {
+		name_value := strings.SplitN(strings.ToLower(strings.TrimSpace(spec)), "=", 2)
+		flag, exists := NameToRestriction[name_value[0]]
+		if !exists {
+			return restriction, fmt.Errorf("Unknown restriction \"%s\"", spec)
+		}
+		if len(name_value) == 1 {
+			restriction.Flags |= flag
+			continue
+		}
+		attribute, is_attribute := attributeOfRestriction(flag)
+		value, err := strconv.Atoi(name_value[1])
+		if !is_attribute || err != nil || value < 1 {
+			return restriction, fmt.Errorf("Invalid restriction \"%s\"", spec)
+		}
+		if restriction.AttributeMinimums == nil {
+			restriction.AttributeMinimums = map[OrbAttribute]int{}
+		}
+		restriction.AttributeMinimums[attribute] = value
+	}
+	return restriction, nil
+}
+
+func (self BoardRestriction) String() string {
+	names := make([]string, 0)
+	for _, name := range restrictionNames {
+		if self.Flags & NameToRestriction[name] != 0 {
+			names = append(names, name)
+		}
+	}
+	minimums := make([]string, 0)
+	for attribute, minimum := range self.AttributeMinimums {
+		minimums = append(minimums, fmt.Sprintf("%s=%d", strings.ToLower(attribute.String()), minimum))
+	}
+	sort.Strings(minimums)
+	return strings.Join(append(names, minimums...), ",")
+}
+
+// Whether the restriction limits any combo.
+func (self BoardRestriction) Enabled() bool {
+	return self.Flags != 0 || len(self.AttributeMinimums) > 0
+}
+
+// Minimum number of orbs a combo needs under the size flags.
+func (self BoardRestriction) MinimumOrbs() int {
+	if self.Flags & RESTRICT_5PLUS != 0 {
+		return 5
+	}
+	if self.Flags & RESTRICT_4PLUS != 0 {
+		return 4
+	}
+	return 3
+}
+
+// Minimum number of orbs a combo of the attribute needs.
+func (self BoardRestriction) MinimumOrbsFor(attribute OrbAttribute) int {
+	if minimum, exists := self.AttributeMinimums[attribute]; exists {
+		return minimum
+	}
+	return self.MinimumOrbs()
+}
+
+// Whether the attribute may be matched. With no attribute flags set, every
+// attribute may be matched.
+func (self BoardRestriction) AllowsAttribute(attribute OrbAttribute) bool {
+	if self.Flags & RESTRICT_ATTRIBUTES == 0 {
+		return true
+	}
+	return self.Flags & AttributeToRestriction[attribute] != 0
+}
+
+func (self BoardRestriction) Allows(combo BoardCombo) bool {
+	return self.AllowsAttribute(combo.Attribute) && len(combo.Positions) >= self.MinimumOrbsFor(combo.Attribute)
+}
+
+// Returns every combo which breaks the restriction.
+func (self BoardRestriction) Violations(combos []BoardCombo) []BoardCombo {
+	violations := make([]BoardCombo, 0)
+	for _, combo := range combos {
+		if !self.Allows(combo) {
+			violations = append(violations, combo)
+		}
+	}
+	return violations
+}
+
+func (self BoardRestriction) IsViolated(combos []BoardCombo) bool {
+	for _, combo := range combos {
+		if !self.Allows(combo) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseRestriction(t *testing.T) {
+	restriction, err := ParseRestriction("5plus, Light")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restriction.Flags != RESTRICT_5PLUS | RESTRICT_LIGHT || len(restriction.AttributeMinimums) != 0 {
+		t.Errorf("Unexpected restriction: %s", restriction)
+	}
+	restriction, err = ParseRestriction("Light=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restriction.MinimumOrbsFor(LIGHT) != 5 || restriction.MinimumOrbsFor(FIRE) != 3 ||
+	   restriction.String() != "light=5" {
+		t.Errorf("Unexpected restriction: %s", restriction)
+	}
+	for _, invalid := range []string{"6plus", "light=x", "5plus=4", "fire=0"} {
+		if _, err := ParseRestriction(invalid); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestBoardRestriction_Violations(t *testing.T) {
+	// R R R R R .
+	// L L L . . .
+	// B B B B . .
+	board := createShapeBoard("RRRRR.", "LLL...", "BBBB..", "......", "......")
+	combos, _ := board.GetCombos()
+
+	tests := []struct {
+		name string
+		restriction BoardRestriction
+		violations int
+	}{
+		{"None", BoardRestriction{}, 0},
+		{"4+", BoardRestriction{RESTRICT_4PLUS, nil}, 1},
+		{"5+", BoardRestriction{RESTRICT_5PLUS, nil}, 2},
+		{"Light only", BoardRestriction{RESTRICT_LIGHT, nil}, 2},
+		{"Fire and Water", BoardRestriction{RESTRICT_FIRE | RESTRICT_WATER, nil}, 1},
+		{"Fire 5+", BoardRestriction{RESTRICT_FIRE | RESTRICT_5PLUS, nil}, 2},
+		{"Light 5+, others 3", BoardRestriction{0, map[OrbAttribute]int{LIGHT: 5}}, 1},
+		{"Water 4, others 5", BoardRestriction{RESTRICT_5PLUS, map[OrbAttribute]int{WATER: 4}}, 1},
+	}
+
+	for _, test := range tests {
+		violations := test.restriction.Violations(combos)
+		if len(violations) != test.violations {
+			t.Errorf("%s: expected %d violations, got %v", test.name, test.violations, violations)
+		}
+		if test.restriction.IsViolated(combos) != (test.violations > 0) {
+			t.Errorf("%s: IsViolated disagrees with Violations", test.name)
+		}
+	}
+}
+
+func TestSolveSettings_RestrictionScoring(t *testing.T) {
+	board := createShapeBoard("RRRRR.", "LLL...", "BBBB..", "......", "......")
+	settings := MakeSolveSettings()
+	settings.ComboMinimum = 3
+	unrestricted := settings.Requirement(board)
+	settings.Restriction = BoardRestriction{0, map[OrbAttribute]int{LIGHT: 5}}
+	restricted := settings.Requirement(board)
+	state := AStarState{board: board}
+
+	// The Light combo is taken off and counted against the state.
+	if restricted.ScoreState(state) != unrestricted.ScoreState(state) - 2 * settings.ComboWeight {
+		t.Errorf("Expected the Light combo to cost 2 combos, got %d vs %d",
+			restricted.ScoreState(state), unrestricted.ScoreState(state))
+	}
+	if !unrestricted.FinishedFn(state) || restricted.FinishedFn(state) {
+		t.Error("Expected only 2 combos to count towards the goal")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Combo counts from several skyfall rollouts of the same board.
+type RolloutStats struct {
+	Mean float64
+	// Total combos of each rollout in ascending order.
+	Counts []int
+}
+
+func (self RolloutStats) String() string {
+	return fmt.Sprintf("Mean: %.2f, P10: %d, P50: %d, P90: %d",
+		self.Mean, self.Percentile(10), self.Percentile(50), self.Percentile(90))
+}
+
+// Nearest-rank percentile of the combo counts, p in [0, 100]: the smallest
+// count with at least p percent of the counts at or below it.
+func (self RolloutStats) Percentile(p float64) int {
+	if len(self.Counts) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p / 100 * float64(len(self.Counts)))) - 1
+	if idx >= len(self.Counts) {
+		idx = len(self.Counts) - 1
+	}
+	if idx < 0 {
+		idx = 0
+	}
+	return self.Counts[idx]
+}
+
+// Runs the given number of skyfall cascades on the board. Rollout i is seeded
+// with seed + i, so the same board always gets the same stats.
+func (self Board) SkyfallRollouts(skyfall *Skyfall, rollouts int, seed int64) RolloutStats {
+	counts := make([]int, rollouts)
+	total := 0
+	for i := 0; i < rollouts; i++ {
+		rollout_skyfall := *skyfall
+		rollout_skyfall.Seed(seed + int64(i))
+		counts[i] = self.GetAllCombosSkyfall(&rollout_skyfall).TotalCombos()
+		total += counts[i]
+	}
+	sort.Ints(counts)
+	mean := 0.0
+	if rollouts > 0 {
+		mean = float64(total) / float64(rollouts)
+	}
+	return RolloutStats{mean, counts}
+}
+
+// Creates a ScoreState function which scores boards by their expected combos
+// over skyfall rollouts instead of guaranteed combos. Means are cached per
+// board hash in a ScoreCache, so this is not safe to share between concurrent
+// solves.
+func MakeExpectedValueScoreFunction(skyfall *Skyfall, rollouts int, seed int64,
+                                    combo_weight int, move_weight int) func(AStarState) int {
+	known_means := MakeScoreCache(SCORE_CACHE_BITS)
+	score_fn := func(state AStarState) int {
+		hash := state.board.Hash()
+		mean, exists := known_means.Get(hash)
+		if !exists {
+			mean = state.board.SkyfallRollouts(skyfall, rollouts, seed).Mean
+			known_means.Set(hash, mean)
+		}
+		return int(mean * float64(combo_weight)) - (move_weight * MoveCost(state.moves))
+	}
+	return score_fn
+}
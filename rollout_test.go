@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSkyfallRollouts_SameSeed_SameStats(t *testing.T) {
+	first := swng_board.SkyfallRollouts(MakeSkyfall(0), 20, 5)
+	second := swng_board.SkyfallRollouts(MakeSkyfall(0), 20, 5)
+
+	if first.Mean != second.Mean {
+		t.Errorf("Means should match: %s vs %s", first, second)
+	}
+	if first.Percentile(0) > first.Percentile(50) || first.Percentile(50) > first.Percentile(100) {
+		t.Errorf("Percentiles should be ordered: %s", first)
+	}
+}
+
+func TestSkyfallRollouts_MeanAtLeastFirstMatch(t *testing.T) {
+	stats := swng_board.SkyfallRollouts(MakeSkyfall(0), 20, 11)
+	first_combos, _ := swng_board.GetCombos()
+
+	if stats.Percentile(0) < len(first_combos) {
+		t.Errorf("Every rollout should have at least %d combos: %s", len(first_combos), stats)
+	}
+}
+
+func TestExpectedValueScore_CachesPerBoard(t *testing.T) {
+	score_fn := MakeExpectedValueScoreFunction(MakeSkyfall(0), 10, 3, 100, 1)
+	state := AStarState{board: swng_board.Clone(), moves: []Direction{RIGHT, RIGHT}}
+
+	first := score_fn(state)
+	second := score_fn(state)
+
+	if first != second {
+		t.Errorf("Scores should be identical, got %d and %d", first, second)
+	}
+	if first <= 0 {
+		t.Errorf("A 10 combo board should score positively, got %d", first)
+	}
+}
+
+func TestExpectedValueScore_KeysByOrbStates(t *testing.T) {
+	score_fn := MakeExpectedValueScoreFunction(MakeSkyfall(0), 10, 3, 100, 1)
+	unmatchable := swng_board.Clone()
+	for i := range unmatchable.Slots {
+		unmatchable.Slots[i].Orb.State |= UNMATCHABLE
+	}
+
+	matching := score_fn(AStarState{board: swng_board.Clone(), moves: []Direction{RIGHT}})
+	blocked := score_fn(AStarState{board: unmatchable, moves: []Direction{RIGHT}})
+	if blocked >= matching {
+		t.Errorf("Unmatchable orbs should not share the cached score, got %d and %d", blocked, matching)
+	}
+}
+
+func TestRolloutStats_Percentile(t *testing.T) {
+	stats := RolloutStats{5.5, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+	expected := map[float64]int{0: 1, 10: 1, 15: 2, 50: 5, 90: 9, 91: 10, 100: 10}
+	for p, count := range expected {
+		if stats.Percentile(p) != count {
+			t.Errorf("P%.0f: expected %d, got %d", p, count, stats.Percentile(p))
+		}
+	}
+}
+
+func TestMoveCost_StraightCheaperThanTurns(t *testing.T) {
+	straight := MoveCost([]Direction{RIGHT, RIGHT, RIGHT, RIGHT})
+	turning := MoveCost([]Direction{RIGHT, DOWN, RIGHT, DOWN})
+
+	if straight != 7 || turning != 12 {
+		t.Errorf("Expected costs 7 and 12, got %d and %d", straight, turning)
+	}
+}
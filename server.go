@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Serves solves over HTTP. Every endpoint takes a POST with the same body as
+// -input, e.g. {"board": "RRB...", "settings": {"combo": 7, "timeout_ms": 500}},
+// where settings are the solve flags along with "width".
+//
+//   POST /solve     Solves the board, responding with a JSONOutput.
+//   POST /combos    Responds with the combos the board makes as it is.
+//   POST /strategy  Solves with YohFindSetup and StrategySolve, responding with
+//                   a JSONOutput and the setup used.
+//
+// Each request has its own settings, and stops when the client disconnects
+// or after its timeout_ms, capped at MaxTimeout.
+type SolveServer struct {
+	// Longest a request may solve for, also used when it has no timeout.
+	MaxTimeout time.Duration
+}
+
+// Largest request body accepted, in bytes.
+const SERVER_MAX_BODY = 1 << 16
+
+type JSONError struct {
+	Error string `json:"error"`
+}
+
+type JSONBoardCombos struct {
+	Board string `json:"board"`
+	Combos []JSONCombo `json:"combos"`
+}
+
+type JSONSetupCombo struct {
+	Attribute string `json:"attribute"`
+	// Placements as [y, x].
+	Positions [][2]int `json:"positions"`
+}
+
+type JSONStrategyOutput struct {
+	JSONOutput
+	// Combos the board is arranged into before the final solve.
+	Setup []JSONSetupCombo `json:"setup"`
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+}
+
+func writeJSONError(writer http.ResponseWriter, status int, err error) {
+	writeJSON(writer, status, JSONError{err.Error()})
+}
+
+// Reads the board and settings from a request, capping its timeout.
+func (self SolveServer) readRequest(writer http.ResponseWriter, request *http.Request) (SolveInput, error) {
+	input, err := ReadJSONInput(http.MaxBytesReader(writer, request.Body, SERVER_MAX_BODY))
+	if err != nil {
+		return SolveInput{}, err
+	}
+	parsed, err := ParseSolveInput(input, nil)
+	if err != nil {
+		return SolveInput{}, err
+	}
+	if parsed.Settings.Timeout <= 0 || parsed.Settings.Timeout > self.MaxTimeout {
+		parsed.Settings.Timeout = self.MaxTimeout
+		parsed.Values["timeout_ms"] = strconv.FormatInt(self.MaxTimeout.Milliseconds(), 10)
+	}
+	return parsed, nil
+}
+
+// Wraps a handler so it only accepts POSTs with a valid body.
+func (self SolveServer) handle(handler func(http.ResponseWriter, *http.Request, SolveInput)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			writer.Header().Set("Allow", http.MethodPost)
+			writeJSONError(writer, http.StatusMethodNotAllowed, fmt.Errorf("Only POST is allowed"))
+			return
+		}
+		parsed, err := self.readRequest(writer, request)
+		if err != nil {
+			writeJSONError(writer, http.StatusBadRequest, err)
+			return
+		}
+		handler(writer, request, parsed)
+	}
+}
+
+func (self SolveServer) solve(writer http.ResponseWriter, request *http.Request, parsed SolveInput) {
+	start_time := time.Now()
+	result := parsed.Settings.Solve(request.Context(), parsed.Board, nil)
+	if result.Reason == CANCELLED {
+		// Nobody is left to respond to.
+		return
+	}
+	output, err := parsed.Output(result, time.Since(start_time))
+	if err != nil {
+		writeJSONError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, output)
+}
+
+func (self SolveServer) combos(writer http.ResponseWriter, request *http.Request, parsed SolveInput) {
+	combos := make([]JSONCombo, 0)
+	for _, combo := range parsed.Board.GetAllCombos() {
+		combos = append(combos, MakeJSONCombo(combo))
+	}
+	writeJSON(writer, http.StatusOK, JSONBoardCombos{parsed.Board.Markup(), combos})
+}
+
+func (self SolveServer) strategy(writer http.ResponseWriter, request *http.Request, parsed SolveInput) {
+	// The setups are all laid out for 6x5 boards.
+	if parsed.Board.Width != 6 {
+		writeJSONError(writer, http.StatusBadRequest, fmt.Errorf("Strategies need a board of width 6"))
+		return
+	}
+	start_time := time.Now()
+	ctx, cancel := parsed.Settings.Context(request.Context())
+	defer cancel()
+	solve_board := parsed.Settings.SolveBoard(parsed.Board)
+	setup := YohFindSetup(solve_board)
+	result := StrategySolve(ctx, solve_board, setup, parsed.Settings.Requirement(solve_board))
+	if result.Reason == CANCELLED {
+		return
+	}
+	output, err := parsed.Output(result, time.Since(start_time))
+	if err != nil {
+		writeJSONError(writer, http.StatusInternalServerError, err)
+		return
+	}
+
+	setup_combos := make([]JSONSetupCombo, len(setup.Combos))
+	for i, combo := range setup.Combos {
+		positions := make([][2]int, len(combo.Positions))
+		for j, placement := range combo.Positions {
+			positions[j] = [2]int{int(placement.Y), int(placement.X)}
+		}
+		setup_combos[i] = JSONSetupCombo{combo.Attribute.String(), positions}
+	}
+	writeJSON(writer, http.StatusOK, JSONStrategyOutput{output, setup_combos})
+}
+
+func (self SolveServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/solve", self.handle(self.solve))
+	mux.HandleFunc("/combos", self.handle(self.combos))
+	mux.HandleFunc("/strategy", self.handle(self.strategy))
+	return mux
+}
+
+func (self SolveServer) ListenAndServe(address string) error {
+	server := &http.Server{
+		Addr: address,
+		Handler: self.Handler(),
+		ReadTimeout: 10 * time.Second,
+		// Responses are only written once the solve is done.
+		WriteTimeout: self.MaxTimeout + 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func postJSON(t *testing.T, url string, body string, result interface{}) int {
+	response, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode
+}
+
+func TestSolveServer_Solve(t *testing.T) {
+	server := httptest.NewServer(SolveServer{5 * time.Second}.Handler())
+	defer server.Close()
+
+	// Different settings at the same time must not affect each other.
+	wait_group := sync.WaitGroup{}
+	for _, combo := range []int{2, 3, 4} {
+		wait_group.Add(1)
+		go func(combo int) {
+			defer wait_group.Done()
+			output := JSONOutput{}
+			body := `{"board": "RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", "settings": {"combo": ` +
+				strconv.Itoa(combo) + `, "timeout_ms": 2000}}`
+			if status := postJSON(t, server.URL + "/solve", body, &output); status != http.StatusOK {
+				t.Errorf("Unexpected status %d", status)
+			}
+			if len(output.Combos) < combo {
+				t.Errorf("Expected at least %d combos, got %d", combo, len(output.Combos))
+			}
+			if output.Settings["combo"] != strconv.Itoa(combo) {
+				t.Errorf("Expected combo %d in the settings, got %s", combo, output.Settings["combo"])
+			}
+		}(combo)
+	}
+	wait_group.Wait()
+}
+
+func TestSolveServer_Combos(t *testing.T) {
+	server := httptest.NewServer(SolveServer{time.Second}.Handler())
+	defer server.Close()
+
+	output := JSONBoardCombos{}
+	body := `{"board": "RRRBBBGGLLDDGGLLDDHHPPJJHHPPJJ", "settings": {"min_match": "3,B=4"}}`
+	if status := postJSON(t, server.URL + "/combos", body, &output); status != http.StatusOK {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	if len(output.Combos) != 1 || output.Combos[0].Attribute != "Fire" {
+		t.Errorf("Expected only the fire combo, got %v", output.Combos)
+	}
+}
+
+func TestSolveServer_Strategy(t *testing.T) {
+	server := httptest.NewServer(SolveServer{500 * time.Millisecond}.Handler())
+	defer server.Close()
+
+	output := JSONStrategyOutput{}
+	body := `{"board": "GHDBDLDGBLGGHLHLRGLDRHLRGLRLBB", "settings": {"combo": 3}}`
+	if status := postJSON(t, server.URL + "/strategy", body, &output); status != http.StatusOK {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	// The solve may run out of time, but should still find a setup.
+	if output.Board != "GHDBDLDGBLGGHLHLRGLDRHLRGLRLBB" || len(output.Setup) == 0 {
+		t.Errorf("Expected a setup, got %+v", output)
+	}
+}
+
+func TestSolveServer_Errors(t *testing.T) {
+	server := httptest.NewServer(SolveServer{time.Second}.Handler())
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		body string
+		status int
+	}{
+		{"/solve", `{"settings": {"combo": 3}}`, http.StatusBadRequest},
+		{"/solve", `{"board": "RRR", "settings": {}}`, http.StatusBadRequest},
+		{"/solve", `{"board": "RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", "settings": {"bogus": 1}}`, http.StatusBadRequest},
+		{"/combos", `not json`, http.StatusBadRequest},
+		{"/strategy", `{"board": "RRHHHDGDGGGDGDDRRRR", "settings": {"width": 5}}`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		output := JSONError{}
+		if status := postJSON(t, server.URL + test.path, test.body, &output); status != test.status {
+			t.Errorf("%s %s: expected %d, got %d", test.path, test.body, test.status, status)
+		}
+		if output.Error == "" {
+			t.Errorf("%s %s: expected an error message", test.path, test.body)
+		}
+	}
+
+	response, err := http.Get(server.URL + "/solve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be rejected, got %d", response.StatusCode)
+	}
+}
+
+func TestSolveServer_CapsTimeout(t *testing.T) {
+	server := httptest.NewServer(SolveServer{200 * time.Millisecond}.Handler())
+	defer server.Close()
+
+	output := JSONOutput{}
+	body := `{"board": "RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", "settings": {"combo": 20, "timeout_ms": 60000}}`
+	if status := postJSON(t, server.URL + "/solve", body, &output); status != http.StatusOK {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	if output.Settings["timeout_ms"] != "200" || output.Stats.ElapsedMs > 2000 {
+		t.Errorf("Expected the timeout to be capped, got %s after %dms",
+			output.Settings["timeout_ms"], output.Stats.ElapsedMs)
+	}
+}
+
+func TestSolveServer_RejectsTooManySearchWorkers(t *testing.T) {
+	server := httptest.NewServer(SolveServer{time.Second}.Handler())
+	defer server.Close()
+
+	body := `{"board": "RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", "settings": {"combo": 8, "search_workers": 20000}}`
+	if status := postJSON(t, server.URL + "/solve", body, &JSONOutput{}); status != http.StatusBadRequest {
+		t.Errorf("Expected %d, got %d", http.StatusBadRequest, status)
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Everything that controls a single solve. Each solve builds its own scoring
+// and rejection functions from these, so settings can be shared between
+// concurrent solves.
+type SolveSettings struct {
+	ComboMinimum int
+	AllowDiagonals bool
+	ComboWeight int
+	MoveWeight int
+	EnhancedWeight int
+	MaxMoves int
+	// Zero or negative is indefinite.
+	Timeout time.Duration
+	// If empty, search all.
+	StartingPositions []Pair
+	MinimumMatch int
+	MinimumMatches map[OrbAttribute]int
+	Rollouts int
+	RolloutSeed int64
+	Restriction BoardRestriction
+	MoveTimer MoveTimer
+	TimeBudget TimeBudget
+	// How to treat hidden orbs, one of "", "avoid" or "expected".
+	Hidden string
+	HiddenSamples int
+	OrbCounts map[OrbAttribute]int
+	// Workers for ParallelAStarSolve, at most GOMAXPROCS. 1 solves with
+	// AStarSolve.
+	SearchWorkers int
+	SearchSeed int64
+	// States kept per path length by BeamSolve. 0 or less solves with
+	// AStarSolve or ParallelAStarSolve instead.
+	BeamWidth int
+}
+
+// Unparsed values of the flags which make up SolveSettings.
+type SolveFlags struct {
+	combo_minimum int
+	allow_diagonals bool
+	combo_weight int
+	move_weight int
+	max_moves int
+	timeout_ms int
+	starting_positions string
+	minimum_match string
+	rollouts int
+	rollout_seed int64
+	enhanced_weight int
+	restriction string
+	steps_per_second float64
+	move_time float64
+	time_extensions string
+	hidden string
+	hidden_samples int
+	orb_counts string
+	search_workers int
+	search_seed int64
+	beam_width int
+}
+
+// Adds every solve setting to the flag set.
+func DefineSolveFlags(flags *flag.FlagSet) *SolveFlags {
+	self := &SolveFlags{}
+	flags.IntVar(&self.combo_minimum, "combo", 7, "Minimum number of combos to stop matching at.")
+	flags.BoolVar(&self.allow_diagonals, "diagonals", false, "Whether to allow diagonals.")
+	flags.IntVar(&self.combo_weight, "combo_weight", 20, "How much combos are scored relative to move count. Higher value calculates faster, lower prioritizes fewer moves.")
+	flags.IntVar(&self.move_weight, "move_weight", 1, "How much moves cost in value for heuristic.")
+	flags.IntVar(&self.max_moves, "max_moves", 50, "Maximum number of allowable moves.")
+	flags.IntVar(&self.timeout_ms, "timeout_ms", -1, "How long to keep calculating (ms) before giving up. Negative is indefinite.")
+	flags.StringVar(&self.starting_positions, "starting_positions", "", "Allowable starting positions in 0-indexed Y-X separated format. e.g. \"0,0|2,1|4,5\".")
+	flags.StringVar(&self.minimum_match, "min_match", "3", "Minimum number of orbs connected to combo, such as Khepri. Per attribute values follow the default, e.g. \"3,H=5,J=none\".")
+	flags.IntVar(&self.rollouts, "rollouts", 0, "Number of skyfall rollouts used to score expected combos. 0 only counts guaranteed combos.")
+	flags.Int64Var(&self.rollout_seed, "rollout_seed", 0, "Seed for skyfall rollouts.")
+	flags.IntVar(&self.enhanced_weight, "enhanced_weight", 0, "How much each matched enhanced orb is scored.")
+	flags.StringVar(&self.restriction, "restrict", "", "Comma separated dungeon restrictions, e.g. \"5plus,light\". One of 4plus, 5plus, fire, water, wood, light, dark, heart, poison, or attribute=orbs for one attribute's minimum, e.g. \"light=5\".")
+	flags.Float64Var(&self.steps_per_second, "steps_per_second", 10, "How many orbs are moved per second in a straight line, turns take half as long again. Used to estimate move time and simulate spinners. 0 disables both.")
+	flags.Float64Var(&self.move_time, "move_time", 0, "Base orb move time in seconds, usually 4 or 5. 0 or lower does not limit the path.")
+	flags.StringVar(&self.time_extensions, "time_extensions", "", "Comma separated seconds added to the move time by leaders and awakenings, negative for hazards. e.g. \"1,0.5,-2\".")
+	flags.StringVar(&self.hidden, "hidden", "", "How to treat cloud and blind orbs. Empty solves with the real orbs, \"avoid\" never relies on hidden orbs, \"expected\" scores expected combos over possible hidden orbs.")
+	flags.IntVar(&self.hidden_samples, "hidden_samples", 20, "Number of samples of hidden orbs used by -hidden=expected.")
+	flags.StringVar(&self.orb_counts, "orb_counts", "", "Total orbs of each attribute, used to guess hidden orbs. e.g. \"R=6,B=5,H=4\".")
+	flags.IntVar(&self.search_workers, "search_workers", 1, "Number of workers the search is split between, at most 64. The result only depends on this and -search_seed, but at most one worker per CPU runs at once. 0 uses one per CPU. Ignored with -rollouts, -hidden=expected or -beam_width.")
+	flags.Int64Var(&self.search_seed, "search_seed", 0, "Seed for splitting the search between workers.")
+	flags.IntVar(&self.beam_width, "beam_width", 0, "Solve with a beam search keeping this many paths of each length, which bounds memory. 0 uses the full search.")
+	return self
+}
+
+// A board and its settings, parsed from a JSONInput.
+type SolveInput struct {
+	Board Board
+	Settings SolveSettings
+	// Value of every setting, in the same format as JSONInput.Settings.
+	Values map[string]string
+}
+
+// Parses the board and settings with a flag set of their own, so inputs never
+// share settings. Defaults fill in any setting the input doesn't give, and are
+// ignored when they aren't solve settings.
+func ParseSolveInput(input JSONInput, defaults map[string]string) (SolveInput, error) {
+	flags := flag.NewFlagSet("input", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	board_markup := flags.String("board", "", "Board markup, see markup.go.")
+	width := flags.Int("width", 6, "Board width. Height will be (width-1)")
+	solve_flags := DefineSolveFlags(flags)
+
+	if err := input.Apply(flags); err != nil {
+		return SolveInput{}, err
+	}
+	given := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	for name, value := range defaults {
+		if given[name] || jsonExcludedSettings[name] || flags.Lookup(name) == nil {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return SolveInput{}, fmt.Errorf("Setting \"%s\": %s", name, err)
+		}
+	}
+
+	if *board_markup == "" {
+		return SolveInput{}, fmt.Errorf("Missing board")
+	}
+	if *width < 5 || *width > 7 {
+		return SolveInput{}, fmt.Errorf("Board width should be in the range [5,7]")
+	}
+	board, err := ParseBoard(*board_markup, *width)
+	if err != nil {
+		return SolveInput{}, err
+	}
+	if int(board.Width) != *width {
+		return SolveInput{}, fmt.Errorf("Board width expected to be %d, got %d", *width, board.Width)
+	}
+	if len(board.Slots) != *width * (*width - 1) {
+		return SolveInput{}, fmt.Errorf("Board size expected to be %d, got %d",
+			*width * (*width - 1), len(board.Slots))
+	}
+	settings, err := solve_flags.Settings(*width)
+	if err != nil {
+		return SolveInput{}, err
+	}
+	values := FlagSettings(flags)
+	// Show the workers used when 0 picks one per CPU.
+	values["search_workers"] = strconv.Itoa(settings.SearchWorkers)
+	return SolveInput{settings.PrepareBoard(board), settings, values}, nil
+}
+
+// Parses "0,0|2,1|4,5" into placements on a board of the given width.
+func ParseStartingPositions(s string, width int) ([]Pair, error) {
+	placements := make([]Pair, 0)
+	if s == "" {
+		return placements, nil
+	}
+	for _, coordinate := range strings.Split(s, "|") {
+		coordinate_vals := strings.Split(coordinate, ",")
+		if len(coordinate_vals) != 2 {
+			return nil, fmt.Errorf("Coordinate string invalid: \"%s\"", coordinate)
+		}
+		y, err := strconv.Atoi(coordinate_vals[0])
+		if err != nil {
+			return nil, err
+		}
+		x, err := strconv.Atoi(coordinate_vals[1])
+		if err != nil {
+			return nil, err
+		}
+		if y < 0 || y >= width - 1 {
+			return nil, fmt.Errorf("Y value %d outside of range [0,%d]", y, width - 1)
+		}
+		if x < 0 || x >= width {
+			return nil, fmt.Errorf("X value %d outside of range [0,%d]", x, width)
+		}
+		placements = append(placements, Pair{uint8(y), uint8(x)})
+	}
+	return placements, nil
+}
+
+// Parses and validates the flags for a board of the given width.
+func (self SolveFlags) Settings(width int) (SolveSettings, error) {
+	settings := SolveSettings{
+		ComboMinimum: self.combo_minimum,
+		AllowDiagonals: self.allow_diagonals,
+		ComboWeight: self.combo_weight,
+		MoveWeight: self.move_weight,
+		EnhancedWeight: self.enhanced_weight,
+		MaxMoves: self.max_moves,
+		Timeout: time.Duration(self.timeout_ms) * time.Millisecond,
+		Rollouts: self.rollouts,
+		RolloutSeed: self.rollout_seed,
+		MoveTimer: MakeMoveTimer(self.steps_per_second),
+		Hidden: self.hidden,
+		HiddenSamples: self.hidden_samples,
+		SearchWorkers: self.search_workers,
+		SearchSeed: self.search_seed,
+		BeamWidth: self.beam_width,
+	}
+	var err error
+	settings.StartingPositions, err = ParseStartingPositions(self.starting_positions, width)
+	if err != nil {
+		return settings, err
+	}
+	settings.MinimumMatch, settings.MinimumMatches, err = ParseMinimumMatches(self.minimum_match)
+	if err != nil {
+		return settings, err
+	}
+	settings.Restriction, err = ParseRestriction(self.restriction)
+	if err != nil {
+		return settings, err
+	}
+	extensions, err := ParseTimeExtensions(self.time_extensions)
+	if err != nil {
+		return settings, err
+	}
+	settings.TimeBudget = TimeBudget{self.move_time, extensions}
+	if self.hidden != "" && self.hidden != "avoid" && self.hidden != "expected" {
+		return settings, fmt.Errorf("Hidden mode should be one of \"avoid\" or \"expected\"")
+	}
+	if self.search_workers < 0 || self.search_workers > MAX_SEARCH_WORKERS {
+		return settings, fmt.Errorf("Search workers should be in the range [0,%d]", MAX_SEARCH_WORKERS)
+	}
+	if self.search_workers == 0 {
+		settings.SearchWorkers = runtime.GOMAXPROCS(0)
+	}
+	settings.OrbCounts, err = ParseOrbCounts(self.orb_counts)
+	return settings, err
+}
+
+// Default settings, the same as the flag defaults.
+func MakeSolveSettings() SolveSettings {
+	flags := flag.NewFlagSet("defaults", flag.ContinueOnError)
+	settings, err := DefineSolveFlags(flags).Settings(6)
+	if err != nil {
+		panic(err)
+	}
+	return settings
+}
+
+// Copy of the board with the minimum matches from the settings.
+func (self SolveSettings) PrepareBoard(board Board) Board {
+	prepared := board.Clone()
+	if self.MinimumMatch > 3 {
+		prepared.MinimumMatch = self.MinimumMatch
+	}
+	prepared.MinimumMatches = self.MinimumMatches
+	return prepared
+}
+
+// Rejects states past the move limits and states whose position was already
+// reached with a score at least as high. Safe for concurrent use.
+func MakeRejectionFunction(settings SolveSettings) func(AStarState) bool {
+	known_boards := MakeTranspositionTable(TRANSPOSITION_BITS)
+	exceeds_limits := MakeLimitRejectionFunction(settings)
+	rejection_fn := func(state AStarState) bool {
+		if exceeds_limits(state) {
+			return true
+		}
+		return !known_boards.Improve(state.TimedHash(settings.MoveTimer, nil), state.score)
+	}
+	return rejection_fn
+}
+
+// Rejects states past the move limits, without remembering any boards.
+func MakeLimitRejectionFunction(settings SolveSettings) func(AStarState) bool {
+	return func(state AStarState) bool {
+		return len(state.moves) > settings.MaxMoves ||
+			!settings.TimeBudget.Allows(settings.MoveTimer, state.moves)
+	}
+}
+
+// Context which ends after the timeout, if any.
+func (self SolveSettings) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	if self.Timeout > 0 {
+		return context.WithTimeout(parent, self.Timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// Board the solver should see, which hides cloud and blind orbs when Hidden
+// is set.
+func (self SolveSettings) SolveBoard(board Board) Board {
+	if self.Hidden != "" {
+		return board.MaskHidden()
+	}
+	return board
+}
+
+// Highest score any path on the board could reach for the default scoring:
+// every orb of each attribute in combos of as few orbs as can match, and every
+// enhanced orb in a combo, less the cost of the moves so far, which only
+// grows. Spinners can change attributes, so with them any three orbs might
+// combo. Nil if a negative weight would make the bound wrong.
+func (self SolveSettings) makeUpperBound(board Board) func(AStarState) int {
+	if self.ComboWeight < 0 || self.EnhancedWeight < 0 || self.MoveWeight < 0 {
+		return nil
+	}
+	counts := map[OrbAttribute]int{}
+	total, enhanced, spinners := 0, 0, false
+	for _, slot := range board.Slots {
+		if slot.Orb.Attribute >= FIRE && slot.Orb.Attribute < UNKNOWN {
+			counts[slot.Orb.Attribute]++
+			total++
+		}
+		if slot.Orb.State & ENHANCED != 0 {
+			enhanced++
+		}
+		if SpinnerInterval(slot.State) > 0 {
+			spinners = true
+		}
+	}
+	max_combos := total / 3
+	if !spinners || !self.MoveTimer.Enabled() {
+		max_combos = 0
+		for attribute, count := range counts {
+			minimum := board.MinimumMatchFor(attribute)
+			if minimum < 3 {
+				minimum = 3
+			}
+			max_combos += count / minimum
+		}
+	}
+	max_score := max_combos * self.ComboWeight + enhanced * self.EnhancedWeight
+	return func(state AStarState) int {
+		return max_score - self.MoveWeight * MoveCost(state.moves)
+	}
+}
+
+// Requirement for solving the board from SolveBoard.
+func (self SolveSettings) Requirement(solve_board Board) SolveRequirement {
+	acceptance_fn := func(state AStarState) bool {
+		combos := state.board.GetAllCombos()
+		return len(combos) - len(self.Restriction.Violations(combos)) >= self.ComboMinimum
+	}
+	scoring_fn := func(state AStarState) int {
+		combos := state.board.GetAllCombos()
+		return len(combos) * self.ComboWeight + CountEnhanced(combos) * self.EnhancedWeight -
+			(self.MoveWeight * MoveCost(state.moves))
+	}
+	if self.Rollouts > 0 {
+		scoring_fn = MakeExpectedValueScoreFunction(MakeSkyfall(0), self.Rollouts,
+			self.RolloutSeed, self.ComboWeight, self.MoveWeight)
+	}
+	if self.Hidden == "expected" {
+		scoring_fn = MakeHiddenScoreFunction(solve_board.HiddenPool(self.OrbCounts), self.HiddenSamples,
+			self.RolloutSeed, self.ComboWeight, self.MoveWeight)
+	}
+	if self.Restriction.Enabled() {
+		// Combos which break the restriction don't count and count against the
+		// state, so the search moves away from them.
+		unrestricted_fn := scoring_fn
+		scoring_fn = func(state AStarState) int {
+			violations := self.Restriction.Violations(state.board.GetAllCombos())
+			return unrestricted_fn(state) - 2 * len(violations) * self.ComboWeight
+		}
+	}
+
+	// Expected scores can be higher than any guaranteed combos.
+	upper_bound_fn := self.makeUpperBound(solve_board)
+	if self.Rollouts > 0 || self.Hidden == "expected" {
+		upper_bound_fn = nil
+	}
+
+	return SolveRequirement{
+		self.AllowDiagonals,
+		// Determines if a state meets the goal.
+		acceptance_fn,
+		// Determines if a state should be ignored.
+		MakeRejectionFunction(self),
+		// Determines and updates a state's score.
+		scoring_fn,
+		// Highest score a state and the paths continuing it could reach.
+		upper_bound_fn,
+		// Allowable starting positions. If empty, search all.
+		self.StartingPositions,
+		// Combos which are not allowed to be matched.
+		self.Restriction,
+		// How fast orbs are moved.
+		self.MoveTimer,
+		// Moves made before the search, none since it starts the path.
+		nil,
+		// Called with each new best path.
+		nil,
+	}
+}
+
+// Whether Solve uses ParallelAStarSolve. Rollouts and expected hidden orbs
+// cache scores per board, so they can't be shared between workers.
+func (self SolveSettings) Parallel() bool {
+	return self.SearchWorkers != 1 && self.BeamWidth <= 0 && self.Rollouts <= 0 && self.Hidden != "expected"
+}
+
+// Solves the board until the combo minimum is met, the timeout passes or the
+// context is done. On_improved is called with each new best path, and may be
+// nil.
+func (self SolveSettings) Solve(parent context.Context, board Board, on_improved func(Moves, int)) SolveResult {
+	ctx, cancel := self.Context(parent)
+	defer cancel()
+	// Only solve with the orbs the player can see.
+	solve_board := self.SolveBoard(board)
+	requirement := self.Requirement(solve_board)
+	requirement.OnImproved = on_improved
+	if self.BeamWidth > 0 {
+		requirement.RejectionFn = MakeLimitRejectionFunction(self)
+		return BeamSolve(ctx, solve_board, requirement, self.BeamWidth)
+	}
+	if self.Parallel() {
+		return ParallelAStarSolve(ctx, solve_board, requirement, self.SearchWorkers, self.SearchSeed)
+	}
+	return AStarSolve(ctx, solve_board, requirement)
+}
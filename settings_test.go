@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestParseStartingPositions(t *testing.T) {
+	placements, err := ParseStartingPositions("0,0|2,1|4,5", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(placements) != 3 || placements[2] != (Pair{4, 5}) {
+		t.Errorf("Unexpected placements %v", placements)
+	}
+	for _, invalid := range []string{"0", "a,0", "5,0", "0,6", "-1,0"} {
+		if _, err := ParseStartingPositions(invalid, 6); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestSolveFlags_Settings(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	solve_flags := DefineSolveFlags(flags)
+	err := flags.Parse([]string{"-combo", "5", "-timeout_ms", "250", "-restrict", "light", "-min_match", "4,H=5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := solve_flags.Settings(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.ComboMinimum != 5 || settings.Timeout != 250 * time.Millisecond ||
+	   settings.Restriction.Flags != RESTRICT_LIGHT {
+		t.Errorf("Unexpected settings %+v", settings)
+	}
+	board := settings.PrepareBoard(CreateRandomBoard(6))
+	if board.MinimumMatchFor(FIRE) != 4 || board.MinimumMatchFor(HEART) != 5 {
+		t.Errorf("Expected minimum matches to be set on the board")
+	}
+
+	flags.Set("search_workers", "4")
+	if settings, err := solve_flags.Settings(6); err != nil || settings.SearchWorkers != 4 {
+		t.Errorf("Expected 4 search workers on any machine, got %d", settings.SearchWorkers)
+	}
+	for _, invalid := range []string{"-1", "65"} {
+		flags.Set("search_workers", invalid)
+		if _, err := solve_flags.Settings(6); err == nil {
+			t.Errorf("Expected an error for %s search workers", invalid)
+		}
+	}
+	flags.Set("search_workers", "1")
+
+	flags.Set("hidden", "sometimes")
+	if _, err := solve_flags.Settings(6); err == nil {
+		t.Error("Expected an error for an unknown hidden mode")
+	}
+}
+
+func TestSolveSettings_SolveStopsWhenCancelled(t *testing.T) {
+	settings := MakeSolveSettings()
+	// Impossible to reach, so only cancelling stops the solve.
+	settings.ComboMinimum = 20
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := settings.Solve(ctx, CreateBoard("RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", 6), nil)
+	if result.Stats.Checked > 1 || result.Reason != CANCELLED {
+		t.Errorf("Expected the solve to be cancelled immediately, checked %d: %s",
+			result.Stats.Checked, result.Reason)
+	}
+}
+
+func TestSolveSettings_SolveTimesOut(t *testing.T) {
+	settings := MakeSolveSettings()
+	settings.ComboMinimum = 20
+	settings.Timeout = 100 * time.Millisecond
+
+	start_time := time.Now()
+	improved := 0
+	result := settings.Solve(context.Background(), CreateBoard("RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", 6),
+		func(moves Moves, score int) {
+			improved++
+		})
+	if elapsed := time.Since(start_time); elapsed > 2 * time.Second || result.Reason != TIMED_OUT {
+		t.Errorf("Expected the solve to time out, took %s: %s", elapsed, result.Reason)
+	}
+	if len(result.Moves.Directions) == 0 || improved == 0 {
+		t.Errorf("Expected the best moves so far, got %s after %d improvements", result.Moves, improved)
+	}
+}
+
+func TestSolveSettings_Parallel(t *testing.T) {
+	settings := MakeSolveSettings()
+	if settings.Parallel() {
+		t.Error("Expected a single worker by default")
+	}
+	settings.SearchWorkers = 2
+	if !settings.Parallel() {
+		t.Error("Expected a parallel solve with 2 workers")
+	}
+	settings.Rollouts = 10
+	if settings.Parallel() {
+		t.Error("Expected rollouts to solve with one worker")
+	}
+
+	settings = MakeSolveSettings()
+	settings.SearchWorkers = 2
+	settings.ComboMinimum = 8
+	result := settings.Solve(context.Background(), swng_board, nil)
+	if result.Reason != GOAL_MET || len(result.Board.GetAllCombos()) < 8 {
+		t.Errorf("Expected at least 8 combos, got %s: %s", result.Moves, result.Reason)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+// Determines every ComboType that the given connected positions satisfy.
+// Shapes follow the in-game rules:
+//  * MATCH_3: Exactly 3 orbs.
+//  * MATCH_TPA: Exactly 4 orbs in a line.
+//  * MATCH_5_LINE: Exactly 5 orbs in a line.
+//  * MATCH_CROSS: Exactly 5 orbs in a plus shape.
+//  * MATCH_L: Exactly 5 orbs, two lines of 3 sharing an end orb.
+//  * MATCH_BOX: Exactly 8 orbs surrounding a single non-matched orb.
+//  * MATCH_VDP: Exactly 9 orbs in a 3x3 square.
+//  * MATCH_ROW: Contains a full horizontal line of the board.
+//  * MATCH_COLUMN: Contains a full vertical line of the board.
+func (self Board) getComboShapes(positions []Pair) []ComboType {
+	shapes := make([]ComboType, 0)
+	if len(positions) == 0 {
+		return shapes
+	}
+
+	min_y, max_y := positions[0].Y, positions[0].Y
+	min_x, max_x := positions[0].X, positions[0].X
+	// Indexed by row and column, which is cheaper than maps for every combo of
+	// every solve.
+	var row_counts, column_counts [256]int
+	for _, placement := range positions {
+		if placement.Y < min_y {
+			min_y = placement.Y
+		}
+		if placement.Y > max_y {
+			max_y = placement.Y
+		}
+		if placement.X < min_x {
+			min_x = placement.X
+		}
+		if placement.X > max_x {
+			max_x = placement.X
+		}
+		row_counts[placement.Y]++
+		column_counts[placement.X]++
+	}
+	height := int(max_y - min_y) + 1
+	width := int(max_x - min_x) + 1
+	count := len(positions)
+	is_line := height == 1 || width == 1
+
+	switch {
+	case count == 3:
+		shapes = append(shapes, MATCH_3)
+	case count == 4 && is_line:
+		shapes = append(shapes, MATCH_TPA)
+	case count == 5 && is_line:
+		shapes = append(shapes, MATCH_5_LINE)
+	case count == 5 && height == 3 && width == 3:
+		center := Pair{min_y + 1, min_x + 1}
+		if containsPair(positions, center) {
+			if row_counts[center.Y] == 3 && column_counts[center.X] == 3 {
+				shapes = append(shapes, MATCH_CROSS)
+			}
+		} else if (row_counts[min_y] == 3 || row_counts[max_y] == 3) &&
+		          (column_counts[min_x] == 3 || column_counts[max_x] == 3) {
+			shapes = append(shapes, MATCH_L)
+		}
+	case count == 8 && height == 3 && width == 3:
+		if !containsPair(positions, Pair{min_y + 1, min_x + 1}) {
+			shapes = append(shapes, MATCH_BOX)
+		}
+	case count == 9 && height == 3 && width == 3:
+		shapes = append(shapes, MATCH_VDP)
+	}
+
+	for y := int(min_y); y <= int(max_y); y++ {
+		if row_counts[y] == int(self.Width) {
+			shapes = append(shapes, MATCH_ROW)
+			break
+		}
+	}
+	for x := int(min_x); x <= int(max_x); x++ {
+		if column_counts[x] == int(self.Height) {
+			shapes = append(shapes, MATCH_COLUMN)
+			break
+		}
+	}
+	return shapes
+}
+
+func containsPair(pairs []Pair, target Pair) bool {
+	for _, pair := range pairs {
+		if pair == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Builds a board from rows where '.' is an empty slot.
+func createShapeBoard(rows ...string) Board {
+	return CreateBoard(strings.ReplaceAll(strings.Join(rows, ""), ".", " "), len(rows[0]))
+}
+
+func TestGetCombos_Shapes(t *testing.T) {
+	tests := []struct {
+		name string
+		board Board
+		expected []ComboType
+	}{
+		{
+			"Three",
+			createShapeBoard("RRR...", "......", "......", "......", "......"),
+			[]ComboType{MATCH_3},
+		},
+		{
+			"TPA horizontal",
+			createShapeBoard("......", ".RRRR.", "......", "......", "......"),
+			[]ComboType{MATCH_TPA},
+		},
+		{
+			"TPA vertical",
+			createShapeBoard("R.....", "R.....", "R.....", "R.....", "......"),
+			[]ComboType{MATCH_TPA},
+		},
+		{
+			"Five in a line",
+			createShapeBoard("......", "......", "RRRRR.", "......", "......"),
+			[]ComboType{MATCH_5_LINE},
+		},
+		{
+			"Cross",
+			createShapeBoard("......", "..R...", ".RRR..", "..R...", "......"),
+			[]ComboType{MATCH_CROSS},
+		},
+		{
+			"T is not a cross",
+			createShapeBoard("......", ".RRR..", "..R...", "..R...", "......"),
+			[]ComboType{},
+		},
+		{
+			"L",
+			createShapeBoard("......", "...R..", "...R..", ".RRR..", "......"),
+			[]ComboType{MATCH_L},
+		},
+		{
+			"Box",
+			createShapeBoard("RRR...", "RBR...", "RRR...", "......", "......"),
+			[]ComboType{MATCH_BOX},
+		},
+		{
+			"VDP",
+			createShapeBoard("......", ".RRR..", ".RRR..", ".RRR..", "......"),
+			[]ComboType{MATCH_VDP},
+		},
+		{
+			"3x4 is not a VDP",
+			createShapeBoard("......", ".RRRR.", ".RRRR.", ".RRRR.", "......"),
+			[]ComboType{},
+		},
+		{
+			"Row",
+			createShapeBoard("......", "......", "......", "......", "RRRRRR"),
+			[]ComboType{MATCH_ROW},
+		},
+		{
+			"Row with extra orb",
+			createShapeBoard("......", "......", "R.....", "R.....", "RRRRRR"),
+			[]ComboType{MATCH_ROW},
+		},
+		{
+			"Column",
+			createShapeBoard(".....R", ".....R", ".....R", ".....R", ".....R"),
+			[]ComboType{MATCH_5_LINE, MATCH_COLUMN},
+		},
+		{
+			"Row of five on 5x4",
+			createShapeBoard(".....", "RRRRR", ".....", "....."),
+			[]ComboType{MATCH_5_LINE, MATCH_ROW},
+		},
+	}
+
+	for _, test := range tests {
+		combos, _ := test.board.GetCombos()
+		if len(combos) != 1 {
+			t.Errorf("%s: expected 1 combo, got %d", test.name, len(combos))
+			continue
+		}
+		shapes := combos[0].Shapes
+		if len(shapes) != len(test.expected) {
+			t.Errorf("%s: expected shapes %v, got %v", test.name, test.expected, shapes)
+			continue
+		}
+		for _, shape := range test.expected {
+			if !combos[0].HasShape(shape) {
+				t.Errorf("%s: expected shapes %v, got %v", test.name, test.expected, shapes)
+				break
+			}
+		}
+	}
+}
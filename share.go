@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Share codes pack a board and its path into URL safe base64, keeping every
+// orb and space state unlike SimpleString. Bits are written most significant
+// first:
+//
+//   version (4), height (4), width (4)
+//   for each space: attribute (4), decorated (1)
+//     if decorated: orb state (6), space state (4)
+//   has path (1)
+//     if has path: start y (4), start x (4), steps (12), each direction - 1 (3)
+//
+// and padded with zeros to a whole byte.
+const SHARE_CODE_VERSION = 1
+
+// Longest path a share code can hold.
+const SHARE_CODE_MAX_STEPS = 1 << 12 - 1
+
+type bitWriter struct {
+	data []byte
+	bits int
+}
+
+func (self *bitWriter) Write(value int, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if self.bits % 8 == 0 {
+			self.data = append(self.data, 0)
+		}
+		if value & (1 << uint(i)) != 0 {
+			self.data[len(self.data) - 1] |= 1 << uint(7 - self.bits % 8)
+		}
+		self.bits++
+	}
+}
+
+type bitReader struct {
+	data []byte
+	bits int
+}
+
+func (self *bitReader) Read(bits int) (int, error) {
+	value := 0
+	for i := 0; i < bits; i++ {
+		if self.bits >= len(self.data) * 8 {
+			return 0, fmt.Errorf("Share code ends early")
+		}
+		bit := (self.data[self.bits / 8] >> uint(7 - self.bits % 8)) & 1
+		value = value << 1 | int(bit)
+		self.bits++
+	}
+	return value, nil
+}
+
+// Encodes the board and moves. Moves without directions are left out.
+func EncodeShareCode(board Board, moves Moves) (string, error) {
+	if board.Width == 0 || board.Width > 15 || board.Height == 0 || board.Height > 15 {
+		return "", fmt.Errorf("A %dx%d board can't be shared", board.Width, board.Height)
+	}
+	if len(moves.Directions) > SHARE_CODE_MAX_STEPS {
+		return "", fmt.Errorf("Paths longer than %d steps can't be shared", SHARE_CODE_MAX_STEPS)
+	}
+	writer := bitWriter{}
+	writer.Write(SHARE_CODE_VERSION, 4)
+	writer.Write(int(board.Height), 4)
+	writer.Write(int(board.Width), 4)
+	for _, slot := range board.Slots {
+		writer.Write(int(slot.Orb.Attribute), 4)
+		if slot.Orb.State == 0 && slot.State == 0 {
+			writer.Write(0, 1)
+			continue
+		}
+		writer.Write(1, 1)
+		writer.Write(int(slot.Orb.State), 6)
+		writer.Write(int(slot.State), 4)
+	}
+	if len(moves.Directions) == 0 {
+		writer.Write(0, 1)
+	} else {
+		writer.Write(1, 1)
+		writer.Write(int(moves.StartingPosition.Y), 4)
+		writer.Write(int(moves.StartingPosition.X), 4)
+		writer.Write(len(moves.Directions), 12)
+		for _, direction := range moves.Directions {
+			writer.Write(int(direction) - int(UP), 3)
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(writer.data), nil
+}
+
+// Decodes a code made by EncodeShareCode. Moves is empty if the code has no
+// path.
+func DecodeShareCode(code string) (Board, Moves, error) {
+	data, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil {
+		return Board{}, Moves{}, fmt.Errorf("Invalid share code: %s", err)
+	}
+	reader := bitReader{data: data}
+	read := func(bits int) int {
+		if err != nil {
+			return 0
+		}
+		var value int
+		value, err = reader.Read(bits)
+		return value
+	}
+
+	if version := read(4); err == nil && version != SHARE_CODE_VERSION {
+		return Board{}, Moves{}, fmt.Errorf("Unsupported share code version %d", version)
+	}
+	height, width := read(4), read(4)
+	if err == nil && (height == 0 || width == 0) {
+		return Board{}, Moves{}, fmt.Errorf("Share code has an empty board")
+	}
+	slots := make([]BoardSpace, height * width)
+	for i := 0; i < len(slots) && err == nil; i++ {
+		attribute := OrbAttribute(read(4))
+		if attribute > UNKNOWN {
+			return Board{}, Moves{}, fmt.Errorf("Unknown attribute %d at %d", attribute, i)
+		}
+		slots[i].Orb.Attribute = attribute
+		if read(1) == 1 {
+			slots[i].Orb.State = OrbStateFlag(read(6))
+			slots[i].State = BoardSpaceStateFlag(read(4))
+		}
+	}
+	board := Board{slots, uint8(height), uint8(width), 3, nil, 0, false}
+
+	moves := Moves{Directions: make([]Direction, 0)}
+	if read(1) == 1 {
+		moves.StartingPosition = Pair{uint8(read(4)), uint8(read(4))}
+		placement := moves.StartingPosition
+		if err == nil && (placement.Y >= board.Height || placement.X >= board.Width) {
+			return Board{}, Moves{}, fmt.Errorf("Path starts off the board at %s", placement)
+		}
+		steps := read(12)
+		for i := 0; i < steps && err == nil; i++ {
+			direction := Direction(read(3) + int(UP))
+			placement = placement.Swap(direction)
+			if placement.Y >= board.Height || placement.X >= board.Width {
+				return Board{}, Moves{}, fmt.Errorf("Path leaves the board at step %d", i + 1)
+			}
+			moves.Directions = append(moves.Directions, direction)
+		}
+	}
+	if err != nil {
+		return Board{}, Moves{}, err
+	}
+	if len(data) * 8 - reader.bits >= 8 {
+		return Board{}, Moves{}, fmt.Errorf("Share code has %d extra bytes", (len(data) * 8 - reader.bits) / 8)
+	}
+	return board, moves, nil
+}
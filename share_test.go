@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestShareCode_RoundTrips(t *testing.T) {
+	board, err := ParseBoard("&R+<G>{%}?.H+.!D~H[L](&B+)RGBLDHJPMo&G.D+{R}_RRGGG", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []Moves{
+		Moves{Pair{3, 0}, []Direction{UP, UP_RIGHT, RIGHT, DOWN_RIGHT, DOWN, DOWN_LEFT, LEFT, UP_LEFT}},
+		Moves{Directions: []Direction{}},
+	}
+
+	for _, moves := range tests {
+		code, err := EncodeShareCode(board, moves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, decoded_moves, err := DecodeShareCode(code)
+		if err != nil {
+			t.Fatalf("%s: %s", code, err)
+		}
+		if decoded.Markup() != board.Markup() || decoded.Height != 5 || decoded.Width != 6 {
+			t.Errorf("Expected\n%s\ngot\n%s", board.Markup(), decoded.Markup())
+		}
+		if decoded_moves.String() != moves.String() {
+			t.Errorf("Expected %s, got %s", moves, decoded_moves)
+		}
+	}
+}
+
+func TestShareCode_IsCompact(t *testing.T) {
+	code, _ := EncodeShareCode(swng_board, Moves{Pair{0, 0}, []Direction{RIGHT, DOWN}})
+	// Plain orbs take 5 bits each.
+	if len(code) > 36 {
+		t.Errorf("Expected a short code, got %d characters: %s", len(code), code)
+	}
+}
+
+func TestDecodeShareCode_Errors(t *testing.T) {
+	board := CreateBoard("RRRBBB", 3)
+	valid, _ := EncodeShareCode(board, Moves{Pair{0, 0}, []Direction{RIGHT}})
+	data, _ := base64.RawURLEncoding.DecodeString(valid)
+	encode := func(data []byte) string {
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"Not base64", "!!!"},
+		{"Empty", ""},
+		{"Truncated", encode(data[:len(data) - 2])},
+		{"Extra bytes", encode(append(append([]byte{}, data...), 0, 0))},
+		{"Unknown version", encode(append([]byte{data[0] | 0xf0}, data[1:]...))},
+		{"Off board", func() string {
+			code, _ := EncodeShareCode(board, Moves{Pair{0, 0}, []Direction{UP}})
+			return code
+		}()},
+	}
+	for _, test := range tests {
+		if _, _, err := DecodeShareCode(test.code); err == nil {
+			t.Errorf("%s: expected an error for %s", test.name, test.code)
+		}
+	}
+}
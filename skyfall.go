@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// Upper bound on cascades, since a degenerate distribution (e.g. a single
+// attribute) would otherwise cascade forever.
+const MAX_SKYFALL_CASCADES = 100
+
+// Describes which orbs fall from the sky to replace comboed orbs.
+type Skyfall struct {
+	// Attributes which fall with equal likelihood before boosts.
+	Attributes []OrbAttribute
+	// Hazard orbs which fall with a combined HazardChance percent.
+	Hazards []OrbAttribute
+	HazardChance float64
+	// Extra percentage points for an attribute, e.g. {LIGHT: 15} for Light+15%.
+	Boosts map[OrbAttribute]float64
+	// Percent chance that a non-hazard orb falls enhanced.
+	EnhanceChance float64
+
+	rng *rand.Rand
+}
+
+// Creates a Skyfall of the six normal orbs which always produces the same
+// orbs for the same seed.
+func MakeSkyfall(seed int64) *Skyfall {
+	return &Skyfall{
+		Attributes: append([]OrbAttribute{}, NormalOrbs[:]...),
+		Hazards: []OrbAttribute{},
+		Boosts: map[OrbAttribute]float64{},
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (self *Skyfall) Seed(seed int64) {
+	self.rng = rand.New(rand.NewSource(seed))
+}
+
+func (self *Skyfall) NextOrb() Orb {
+	if self.rng == nil {
+		self.Seed(0)
+	}
+	if len(self.Hazards) > 0 && self.rng.Float64() * 100 < self.HazardChance {
+		return Orb{self.Hazards[self.rng.Intn(len(self.Hazards))], 0}
+	}
+	if len(self.Attributes) == 0 {
+		return Orb{EMPTY, 0}
+	}
+
+	// Boosts take their share first, the rest is split evenly.
+	total_boost := 0.0
+	for _, attribute := range self.Attributes {
+		total_boost += self.Boosts[attribute]
+	}
+	base := 0.0
+	if total_boost < 100 {
+		base = (100 - total_boost) / float64(len(self.Attributes))
+	}
+	total := base * float64(len(self.Attributes)) + total_boost
+
+	roll := self.rng.Float64() * total
+	attribute := self.Attributes[len(self.Attributes) - 1]
+	for _, candidate := range self.Attributes {
+		roll -= base + self.Boosts[candidate]
+		if roll < 0 {
+			attribute = candidate
+			break
+		}
+	}
+	orb := Orb{attribute, 0}
+	if self.EnhanceChance > 0 && self.rng.Float64() * 100 < self.EnhanceChance {
+		orb.State |= ENHANCED
+	}
+	return orb
+}
+
+// Fills every EMPTY slot with a new orb and marks it in from_skyfall.
+// Expects orbs to have already been dropped.
+func (self *Skyfall) Fill(board Board, from_skyfall []bool) {
+	for i := 0; i < len(board.Slots); i++ {
+		if board.Slots[i].Orb.Attribute != EMPTY {
+			continue
+		}
+		board.Slots[i].Orb = self.NextOrb()
+		from_skyfall[i] = true
+	}
+}
+
+type Cascade struct {
+	// Combos made only from orbs on the board when matching started.
+	Guaranteed []BoardCombo
+	// Combos including at least one orb that fell from the sky.
+	Skyfall []BoardCombo
+	// The board after all cascades are finished.
+	Board Board
+}
+
+func (self Cascade) TotalCombos() int {
+	return len(self.Guaranteed) + len(self.Skyfall)
+}
+
+// Like GetAllCombos, but refills the board from skyfall after every drop and
+// keeps cascading until nothing matches.
+func (self Board) GetAllCombosSkyfall(skyfall *Skyfall) Cascade {
+	result := Cascade{make([]BoardCombo, 0), make([]BoardCombo, 0), Board{}}
+	current_board := self.Clone()
+	from_skyfall := make([]bool, len(self.Slots))
+	for cascades := 0; cascades < MAX_SKYFALL_CASCADES; cascades++ {
+		new_combos, next_board := current_board.GetCombos()
+		if len(new_combos) == 0 {
+			break
+		}
+		current_board = next_board
+		for _, combo := range new_combos {
+			is_skyfall := false
+			for _, placement := range combo.Positions {
+				pos := placement.ToPos(current_board)
+				is_skyfall = is_skyfall || from_skyfall[pos]
+				current_board.Slots[pos].Orb = Orb{EMPTY, 0}
+			}
+			if is_skyfall {
+				result.Skyfall = append(result.Skyfall, combo)
+			} else {
+				result.Guaranteed = append(result.Guaranteed, combo)
+			}
+		}
+		current_board.dropOrbsTracked(from_skyfall)
+		skyfall.Fill(current_board, from_skyfall)
+	}
+	result.Board = current_board
+	return result
+}
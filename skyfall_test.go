@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSkyfall_SameSeed_SameCascade(t *testing.T) {
+	first := swng_board.GetAllCombosSkyfall(MakeSkyfall(42))
+	second := swng_board.GetAllCombosSkyfall(MakeSkyfall(42))
+
+	if first.Board.SimpleString() != second.Board.SimpleString() {
+		t.Errorf("Boards should match:\n%s\n%s", first.Board, second.Board)
+	}
+	if first.TotalCombos() != second.TotalCombos() {
+		t.Errorf("Combo counts should match: %d vs %d", first.TotalCombos(), second.TotalCombos())
+	}
+}
+
+func TestSkyfall_FirstMatch_IsGuaranteed(t *testing.T) {
+	cascade := swng_board.GetAllCombosSkyfall(MakeSkyfall(7))
+	first_combos, _ := swng_board.GetCombos()
+
+	// Nothing has fallen yet during the first match.
+	if len(cascade.Guaranteed) < len(first_combos) {
+		t.Errorf("Expected at least %d guaranteed combos, got %d",
+			len(first_combos), len(cascade.Guaranteed))
+	}
+	for _, slot := range cascade.Board.Slots {
+		if slot.Orb.Attribute == EMPTY {
+			t.Fatalf("Skyfall should fill every slot:\n%s", cascade.Board)
+		}
+	}
+}
+
+func TestSkyfall_SingleAttribute_CombosFromSkyfall(t *testing.T) {
+	// G G G R B L
+	// R B L D H R
+	// B L D H R B
+	// L D H R B L
+	// D H R B L D
+	board := CreateBoard("GGGRBLRBLDHRBLDHRBLDHRBLDHRBLD", 6)
+	skyfall := MakeSkyfall(1)
+	skyfall.Attributes = []OrbAttribute{WOOD}
+
+	cascade := board.GetAllCombosSkyfall(skyfall)
+
+	if len(cascade.Guaranteed) != 1 {
+		t.Errorf("Expected 1 guaranteed combo, got %d", len(cascade.Guaranteed))
+	}
+	if len(cascade.Skyfall) == 0 {
+		t.Error("Wood skyfall should create more wood combos.")
+	}
+}
+
+func TestSkyfall_Boost_FavorsAttribute(t *testing.T) {
+	skyfall := MakeSkyfall(3)
+	skyfall.Boosts[LIGHT] = 50
+
+	counts := map[OrbAttribute]int{}
+	for i := 0; i < 6000; i++ {
+		counts[skyfall.NextOrb().Attribute]++
+	}
+	// Light should be 50% + 50%/6 ~= 58%, others ~8%.
+	if counts[LIGHT] < 3000 || counts[FIRE] > 1000 {
+		t.Errorf("Unexpected distribution: %v", counts)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"container/heap"
+	"context"
 	"fmt"
 )
 
@@ -13,8 +14,21 @@ type SolveRequirement struct {
 	RejectionFn func(AStarState) bool
 	// Determines and updates a state's score.
 	ScoreState func(AStarState) int
+	// Highest score the state or any path continuing it could reach, if known.
+	// ParallelAStarSolve drops states which can't beat the best so far.
+	UpperBound func(AStarState) int
 	// Determine allowable starting positions. If empty slice, search all.
 	StartingPositions []Pair
+	// States whose combos break the restriction are never taken as the best.
+	// ScoreState should also score them lower so they aren't searched first.
+	Restriction BoardRestriction
+	// How fast orbs are moved, used to spin spinners along the path.
+	MoveTimer MoveTimer
+	// Moves made before the search's first step, when the search continues a
+	// path, so spinners keep the time the path has already taken.
+	PriorDirections []Direction
+	// Called with each new best path and its score, if set.
+	OnImproved func(Moves, int)
 }
 
 type Moves struct {
@@ -59,6 +73,17 @@ var DirectionReverse map[Direction]Direction = map[Direction]Direction {
 	UP_RIGHT: DOWN_LEFT,
 }
 
+// Seconds since the start of the whole path before and after the last of the
+// moves, summed the same way as ApplyMoves so spinners tick at the same steps.
+func (self SolveRequirement) stepSeconds(moves []Direction) (float64, float64) {
+	path := moves
+	if len(self.PriorDirections) > 0 {
+		path = append(append([]Direction{}, self.PriorDirections...), moves...)
+	}
+	start := self.MoveTimer.PathSeconds(path[:len(path) - 1])
+	return start, start + self.MoveTimer.StepSeconds(path, len(path) - 1)
+}
+
 func (self AStarState) NextStates(requirements SolveRequirement) []AStarState {
 	next_states := make([]AStarState, 0)
 	reverse_move := DirectionReverse[self.moves[len(self.moves) - 1]]
@@ -75,20 +100,52 @@ func (self AStarState) NextStates(requirements SolveRequirement) []AStarState {
 		   next_placement.X >= self.board.Width {
 			continue
 		}
-		next_state := self.Clone()
-		new_board, err := next_state.board.Swap(self.current_pos, direction)
+		// Swap copies the board, so there's no need to clone the state.
+		new_board, err := self.board.Swap(self.current_pos, direction)
 		if err != nil {
 			continue
 		}
-		next_state.board = new_board
-		next_state.moves = append(next_state.moves, direction)
-		next_state.current_pos = next_placement
+		next_moves := make([]Direction, len(self.moves), len(self.moves) + 1)
+		copy(next_moves, self.moves)
+		next_state := AStarState{
+			board: new_board,
+			starting_pos: self.starting_pos,
+			current_pos: next_placement,
+			moves: append(next_moves, direction),
+		}
+		if requirements.MoveTimer.Enabled() {
+			start, end := requirements.stepSeconds(next_state.moves)
+			next_state.board.TickSpinners(start, end, next_placement)
+		}
 		// next_state.parent = &self
 		next_states = append(next_states, next_state)
 	}
 	return next_states
 }
 
+// Cost of a path where continuing in the same direction is cheaper than
+// turning.
+func MoveCost(moves []Direction) int {
+	if len(moves) == 0 {
+		return 0
+	}
+	move_cost := 3
+	total_cost := 3
+	last_move := moves[0]
+	for _, move := range moves[1:] {
+		if move == last_move {
+			if move_cost > 1 {
+				move_cost--
+			}
+		} else {
+			move_cost = 3
+		}
+		last_move = move
+		total_cost += move_cost
+	}
+	return total_cost
+}
+
 // Implementation of Heap which keeps a circular queue as underlying data.
 type StatePriorityQueue struct {
 	data []*AStarState
@@ -187,10 +244,79 @@ func (q *CircularQueue) Pop() *AStarState {
 	return node
 }
 
-func AStarSolve(board Board, requirements SolveRequirement) Moves {
+// Why a solve stopped.
+type StopReason uint8
+
+const (
+	// FinishedFn accepted the best state.
+	GOAL_MET StopReason = iota
+	// The context's deadline passed.
+	TIMED_OUT
+	// Every state was checked or rejected.
+	EXHAUSTED
+	// The context was cancelled.
+	CANCELLED
+)
+
+var StopReasonToName map[StopReason]string = map[StopReason]string{
+	GOAL_MET: "Goal met",
+	TIMED_OUT: "Timed out",
+	EXHAUSTED: "Exhausted",
+	CANCELLED: "Cancelled",
+}
+
+func (self StopReason) String() string {
+	return StopReasonToName[self]
+}
+
+// How much work a solve took.
+type SolveStats struct {
+	// States taken off the queue.
+	Checked int
+	// States rejected while expanding.
+	Skipped int
+	// Score of the returned state.
+	Score int
+}
+
+// The best state found by a solve, and why it stopped.
+type SolveResult struct {
+	Moves Moves
+	// Board the solver saw after the moves, before any combos are cleared.
+	Board Board
+	Stats SolveStats
+	Reason StopReason
+}
+
+// Pops the best state, or nil once the queue is empty.
+func popState(queue *StatePriorityQueue) *AStarState {
+	if queue.Len() == 0 {
+		return nil
+	}
+	return heap.Pop(queue).(*AStarState)
+}
+
+// Reason the context stopped the solve, if it did.
+func contextStopReason(ctx context.Context) (StopReason, bool) {
+	select {
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return TIMED_OUT, true
+		}
+		return CANCELLED, true
+	default:
+		return GOAL_MET, false
+	}
+}
+
+// Searches until the requirements are met, every state is exhausted or the
+// context is done, returning the best state found so far.
+func AStarSolve(ctx context.Context, board Board, requirements SolveRequirement) SolveResult {
 	// Initialize States
 	// queue := CircularQueue{nodes: make([]*AStarState, 1 << 10)}
 	queue := MakePriorityQueue(1 << 10)
+	// Keep the hash of every state up to date as it is swapped.
+	board.Rehash()
 	moves := []Direction{RIGHT, DOWN, LEFT, UP}
 	if requirements.AllowDiagonals {
 		moves = []Direction{RIGHT, DOWN_RIGHT, DOWN, DOWN_LEFT, LEFT, UP_LEFT, UP, UP_RIGHT}
@@ -210,6 +336,10 @@ func AStarSolve(board Board, requirements SolveRequirement) Moves {
 			if err != nil {
 				continue
 			}
+			if requirements.MoveTimer.Enabled() {
+				start, end := requirements.stepSeconds([]Direction{move})
+				board.TickSpinners(start, end, starting_pos.Swap(move))
+			}
 			new_state := AStarState{
 				board,
 				starting_pos,
@@ -223,19 +353,23 @@ func AStarSolve(board Board, requirements SolveRequirement) Moves {
 			}
 		}
 	}
-	fmt.Printf("Queue initial size: %d\n", queue.count)
 
 	best_state := AStarState{score: -100000}
 
 	checked := 0
 	skipped := 0
+	reason := GOAL_MET
 
 	var last_ptr *AStarState = nil
-	for state_ptr := heap.Pop(&queue).(*AStarState);
+	for state_ptr := popState(&queue);
 			!requirements.FinishedFn(best_state);
-			state_ptr = heap.Pop(&queue).(*AStarState) {
+			state_ptr = popState(&queue) {
 		if state_ptr == nil {
-			fmt.Println("Ran out of boards to check, exiting.")
+			reason = EXHAUSTED
+			break
+		}
+		if stop_reason, stopped := contextStopReason(ctx); stopped {
+			reason = stop_reason
 			break
 		}
 		if state_ptr == last_ptr {
@@ -247,12 +381,13 @@ func AStarSolve(board Board, requirements SolveRequirement) Moves {
 
 		requirements.ScoreState(current_state)
 		// fmt.Printf("%s\n", current_state.board.GetAllCombos())
-		if current_state.score > best_state.score {
+		if current_state.score > best_state.score &&
+		   (!requirements.Restriction.Enabled() ||
+		    !requirements.Restriction.IsViolated(current_state.board.GetAllCombos())) {
 			best_state = current_state
-			// best_state.Combos = len(current_state.board.GetAllCombos())
-			current_moves := Moves{current_state.starting_pos, current_state.moves}
-			// fmt.Println(best_state.board)
-			fmt.Printf("Current best with score of %d\n%s\n", best_state.score, current_moves)
+			if requirements.OnImproved != nil {
+				requirements.OnImproved(Moves{best_state.starting_pos, best_state.moves}, best_state.score)
+			}
 		}
 		next_states := current_state.NextStates(requirements)
 		// 		for _, next_state := range current_state.NextStates() {
@@ -268,17 +403,17 @@ func AStarSolve(board Board, requirements SolveRequirement) Moves {
 			// queue.Push(&next_state)
 			heap.Push(&queue, &next_state)
 		}
-		// Check every 1,000,000 iterations
 		checked++
-		if (checked + skipped) % 1000000 == 0 {
-			fmt.Printf("Checked: %d, Skipped: %d\n", checked, skipped)
-		}
 	}
-	fmt.Printf("Finished after %d checks with %d skipped.\n", checked, skipped)
-	fmt.Println(best_state.board)
-	combos := best_state.board.GetAllCombos()
-	for _, combo := range combos {
-		combo.Print(board.Width)
+
+	result_board := best_state.board
+	if best_state.moves == nil {
+		result_board = board
+	}
+	return SolveResult{
+		Moves{best_state.starting_pos, best_state.moves},
+		result_board,
+		SolveStats{checked, skipped, best_state.score},
+		reason,
 	}
-	return Moves{best_state.starting_pos, best_state.moves}
 }
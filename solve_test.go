@@ -1,4 +1,10 @@
 package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
 //
 // import (
 // 	"fmt"
@@ -71,3 +77,73 @@ package main
 // 		t.Error("Expected the next two movements to be right and down.")
 // 	}
 // }
+
+func makeTestRequirement(combos int, max_moves int) SolveRequirement {
+	settings := MakeSolveSettings()
+	settings.ComboMinimum = combos
+	settings.MaxMoves = max_moves
+	return settings.Requirement(swng_board)
+}
+
+func TestAStarSolve_StopReasons(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	timed_out, cancel_timeout := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+	defer cancel_timeout()
+
+	tests := []struct {
+		name string
+		ctx context.Context
+		requirement SolveRequirement
+		reason StopReason
+	}{
+		{"Goal met", context.Background(), makeTestRequirement(3, 50), GOAL_MET},
+		{"Exhausted", context.Background(), makeTestRequirement(20, 1), EXHAUSTED},
+		{"Cancelled", cancelled, makeTestRequirement(20, 50), CANCELLED},
+		{"Timed out", timed_out, makeTestRequirement(20, 50), TIMED_OUT},
+	}
+	for _, test := range tests {
+		result := AStarSolve(test.ctx, swng_board, test.requirement)
+		if result.Reason != test.reason {
+			t.Errorf("%s: expected %s, got %s", test.name, test.reason, result.Reason)
+		}
+		moved, err := swng_board.ApplyMoves(result.Moves, MoveTimer{})
+		if err != nil || moved.SimpleString() != result.Board.SimpleString() {
+			t.Errorf("%s: expected the board after %s", test.name, result.Moves)
+		}
+	}
+}
+
+func TestAStarSolve_StreamsImprovements(t *testing.T) {
+	requirement := makeTestRequirement(8, 50)
+	scores := []int{}
+	last_moves := Moves{}
+	requirement.OnImproved = func(moves Moves, score int) {
+		scores = append(scores, score)
+		last_moves = moves
+	}
+
+	result := AStarSolve(context.Background(), swng_board, requirement)
+	if len(scores) == 0 {
+		t.Fatal("Expected improvements to be streamed")
+	}
+	for i := 1; i < len(scores); i++ {
+		if scores[i] <= scores[i - 1] {
+			t.Errorf("Expected increasing scores, got %v", scores)
+		}
+	}
+	if scores[len(scores) - 1] != result.Stats.Score || last_moves.String() != result.Moves.String() {
+		t.Errorf("Expected the last improvement %s to be the result %s", last_moves, result.Moves)
+	}
+}
+
+func TestStrategySolve_StopsWhenCancelled(t *testing.T) {
+	board := CreateBoard("GHDBDLDGBLGGHLHLRGLDRHLRGLRLBB", 6)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := StrategySolve(ctx, board, YohFindSetup(board), makeTestRequirement(7, 50))
+	if result.Reason != CANCELLED || len(result.Moves.Directions) != 0 {
+		t.Errorf("Expected no moves once cancelled, got %s: %s", result.Moves, result.Reason)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+)
+
+// Order which spinners cycle orbs through. Orbs which aren't in the cycle,
+// e.g. UNKNOWN or hazards, don't spin. A copy, so changing the cycle leaves
+// NormalOrbs alone.
+var SpinnerCycle []OrbAttribute = append([]OrbAttribute{}, NormalOrbs[:]...)
+
+func SpinnerInterval(state BoardSpaceStateFlag) float64 {
+	if state & SPINNER_1S != 0 {
+		return 1
+	}
+	if state & SPINNER_2S != 0 {
+		return 2
+	}
+	return 0
+}
+
+func nextSpinnerAttribute(attribute OrbAttribute) OrbAttribute {
+	for i, candidate := range SpinnerCycle {
+		if candidate == attribute {
+			return SpinnerCycle[(i + 1) % len(SpinnerCycle)]
+		}
+	}
+	return attribute
+}
+
+// Spins every spinner once for each of its intervals that passed between start
+// and end seconds. The orb at held is in the player's hand and is not spun.
+// Modifies the board in place.
+func (self *Board) TickSpinners(start float64, end float64, held Pair) {
+	held_pos := int(held.ToPos(*self))
+	for i := 0; i < len(self.Slots); i++ {
+		interval := SpinnerInterval(self.Slots[i].State)
+		if interval == 0 || i == held_pos || self.Slots[i].Orb.Attribute == EMPTY {
+			continue
+		}
+		ticks := int(math.Floor(end / interval) - math.Floor(start / interval))
+		if ticks <= 0 {
+			continue
+		}
+		old_orb := self.Slots[i].Orb
+		for tick := 0; tick < ticks; tick++ {
+			self.Slots[i].Orb.Attribute = nextSpinnerAttribute(self.Slots[i].Orb.Attribute)
+		}
+		if self.hashed {
+			self.hash ^= zobristOrb(i, old_orb) ^ zobristOrb(i, self.Slots[i].Orb)
+		}
+	}
+}
+
+// Plays the moves on the board, spinning spinners as the timer says time
+// passes, and returns the board once the held orb is released.
+func (self Board) ApplyMoves(moves Moves, timer MoveTimer) (Board, error) {
+	current_board := self.Clone()
+	placement := moves.StartingPosition
+	elapsed := 0.0
+	for i, direction := range moves.Directions {
+		new_board, err := current_board.Swap(placement, direction)
+		if err != nil {
+			return current_board, err
+		}
+		current_board = new_board
+		placement = placement.Swap(direction)
+		if timer.Enabled() {
+			step := timer.StepSeconds(moves.Directions, i)
+			current_board.TickSpinners(elapsed, elapsed + step, placement)
+			elapsed += step
+		}
+	}
+	return current_board, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTickSpinners_FollowsInterval(t *testing.T) {
+	board, _ := ParseBoard("[R](R)[R]RRR", 3)
+
+	board.TickSpinners(0, 1.5, Pair{0, 2})
+	if board.Slots[0].Orb.Attribute != WATER || board.Slots[1].Orb.Attribute != FIRE {
+		t.Errorf("Only the 1s spinner should have spun:\n%s", board)
+	}
+	if board.Slots[2].Orb.Attribute != FIRE {
+		t.Errorf("The held orb should not spin:\n%s", board)
+	}
+
+	board.TickSpinners(1.5, 2, Pair{1, 0})
+	if board.Slots[0].Orb.Attribute != WOOD || board.Slots[1].Orb.Attribute != WATER {
+		t.Errorf("Both spinners should have spun:\n%s", board)
+	}
+}
+
+func TestTickSpinners_LeavesOrbsOutsideTheCycle(t *testing.T) {
+	board, _ := ParseBoard("[_](J)[P]{%}[M](R)", 3)
+	board.Rehash()
+	hash := board.Hash()
+
+	board.TickSpinners(0, 4, Pair{9, 9})
+	for i, attribute := range []OrbAttribute{UNKNOWN, JAMMER, POISON, UNKNOWN, MORTAL_POISON, WOOD} {
+		if board.Slots[i].Orb.Attribute != attribute {
+			t.Errorf("Space %d: expected %s, got %s", i, attribute, board.Slots[i].Orb.Attribute)
+		}
+	}
+	if board.Hash() == hash || board.Clone().Hash() != board.Hash() {
+		t.Error("Only the spun Fire orb should change the hash.")
+	}
+}
+
+func TestSpinnerCycle_CopiesNormalOrbs(t *testing.T) {
+	SpinnerCycle[0] = HEART
+	defer func() { SpinnerCycle[0] = NormalOrbs[0] }()
+	if NormalOrbs[0] != FIRE || MakeSkyfall(1).Attributes[0] != FIRE {
+		t.Error("Changing the spinner cycle should leave the normal orbs alone.")
+	}
+
+	skyfall := MakeSkyfall(1)
+	skyfall.Attributes[0] = HEART
+	if NormalOrbs[0] != FIRE || MakeSkyfall(1).Attributes[0] != FIRE {
+		t.Error("Changing a skyfall's attributes should leave the normal orbs alone.")
+	}
+}
+
+func TestApplyMoves_LingeringOnSpinnerChangesBoard(t *testing.T) {
+	// R [G] B
+	// L  D  H
+	board, _ := ParseBoard("R[G]BLDH", 3)
+	// Hold L and move it along the bottom row and back, never over the spinner,
+	// so only the time the path takes spins it.
+	moves := Moves{Pair{1, 0}, []Direction{RIGHT, RIGHT, LEFT, LEFT}}
+
+	still, _ := board.ApplyMoves(moves, MoveTimer{})
+	if still.Slots[1].Orb.Attribute != WOOD || still.SimpleString() != board.SimpleString() {
+		t.Errorf("Without timing nothing should spin:\n%s", still)
+	}
+
+	// Two steps a second, so the spinner turns after the second and fourth step.
+	spun, err := board.ApplyMoves(moves, MoveTimer{0.5, 0.5, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spun.Slots[1].Orb.Attribute != DARK {
+		t.Errorf("Expected the spinner to turn the wood orb twice:\n%s", spun)
+	}
+}
+
+func TestApplyMoves_SpinsWithMoveTimer(t *testing.T) {
+	// R [G] B
+	// L  D  H
+	board, _ := ParseBoard("R[G]BLDH", 3)
+	board.Rehash()
+	// Turns take 0.5s and straight steps 0.25s, so the path takes 1.25s and the
+	// spinner turns once, during the last step.
+	timer := MoveTimer{0.25, 0.5, 1}
+	moves := Moves{Pair{1, 0}, []Direction{RIGHT, RIGHT, UP}}
+
+	spun, err := board.ApplyMoves(moves, timer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spun.Slots[1].Orb.Attribute != LIGHT {
+		t.Errorf("Expected the spinner to turn once:\n%s", spun)
+	}
+
+	// The solver's states should spin the same way.
+	requirements := SolveRequirement{MoveTimer: timer}
+	first, _ := board.Swap(moves.StartingPosition, RIGHT)
+	first.TickSpinners(0, timer.StepSeconds(moves.Directions, 0), Pair{1, 1})
+	state := AStarState{first, moves.StartingPosition, Pair{1, 1}, moves.Directions[:1], 0}
+	for _, direction := range moves.Directions[1:] {
+		for _, next_state := range state.NextStates(requirements) {
+			if next_state.moves[len(next_state.moves) - 1] == direction {
+				state = next_state
+			}
+		}
+	}
+	if state.board.SimpleString() != spun.SimpleString() || state.board.Hash() != spun.Hash() {
+		t.Errorf("Expected NextStates to match ApplyMoves:\n%s\n%s", state.board, spun)
+	}
+}
+
+func TestAStarSolve_ContinuesSpinnerTimeFromPriorDirections(t *testing.T) {
+	board, _ := ParseBoard("LL[G]RBHDHB", 3)
+	timer := MoveTimer{0.25, 0.5, 1}
+	prior := Moves{Pair{2, 0}, []Direction{RIGHT, RIGHT}}
+	moved, _ := board.ApplyMoves(prior, timer)
+
+	// Continuing at 0.75s, the spinner turns to Light during the next step.
+	requirements := SolveRequirement{
+		FinishedFn: func(state AStarState) bool { return len(state.board.GetAllCombos()) > 0 },
+		RejectionFn: func(state AStarState) bool { return len(state.moves) > 1 },
+		ScoreState: func(state AStarState) int { return len(state.board.GetAllCombos()) },
+		StartingPositions: []Pair{Pair{2, 2}},
+		MoveTimer: timer,
+		PriorDirections: prior.Directions,
+	}
+	result := AStarSolve(context.Background(), moved, requirements)
+	if result.Reason != GOAL_MET {
+		t.Fatalf("Expected the spinner to make a combo: %s", result.Reason)
+	}
+	full := Moves{prior.StartingPosition, append(append([]Direction{}, prior.Directions...), result.Moves.Directions...)}
+	replayed, _ := board.ApplyMoves(full, timer)
+	if result.Board.SimpleString() != replayed.SimpleString() {
+		t.Errorf("Expected the search to match replaying the whole path:\n%s\n%s", result.Board, replayed)
+	}
+}
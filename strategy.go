@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -50,7 +51,7 @@ func (self BoardSetup) String() string {
 		slots[pos].Orb.Attribute = attribute
 	}
 
-	board := Board{slots, 5, 6, 0}
+	board := Board{slots, 5, 6, 0, nil, 0, false}
 
 	return fmt.Sprintf("Board Setup:\n%s\n", board.String())
 }
@@ -200,8 +201,9 @@ func (self BoardSetup) UnusedOrbIdxs(board Board) []Pair {
 	return result
 }
 
-// Given a BoardSetup, solve using a combo by combo basis.
-func StrategySolve(board Board, setup BoardSetup, requirements SolveRequirement) Moves {
+// Given a BoardSetup, solve using a combo by combo basis. Stops early with the
+// moves so far once the context is done.
+func StrategySolve(ctx context.Context, board Board, setup BoardSetup, requirements SolveRequirement) SolveResult {
 	unused_idxs := setup.UnusedOrbIdxs(board)
 	var starting_positions []Pair = make([]Pair, 0)
 	if len(requirements.StartingPositions) == 0 {
@@ -223,9 +225,18 @@ func StrategySolve(board Board, setup BoardSetup, requirements SolveRequirement)
 
 	current_board := board.Clone()
 	moves := Moves{}
+	stats := SolveStats{}
+	// Board after the moves so far, without the tape keeping each combo in place.
+	final_board := func() Board {
+		moved, err := board.ApplyMoves(moves, requirements.MoveTimer)
+		if err != nil {
+			panic("Error occurred when expecting it not to.")
+		}
+		return moved
+	}
 
-	for _, combo := range setup.Combos {
-		sub_known_boards := map[string]int{}
+	for i, combo := range setup.Combos {
+		sub_known_boards := MakeTranspositionTable(TRANSPOSITION_MIN_BITS)
 
 		sub_requirements := SolveRequirement {
 			AllowDiagonals: requirements.AllowDiagonals,
@@ -238,15 +249,10 @@ func StrategySolve(board Board, setup BoardSetup, requirements SolveRequirement)
 				return true
 			},
 			RejectionFn: func(state AStarState) bool {
-				key := state.current_pos.String() + state.board.SimpleString()
-				if old_val, exists := sub_known_boards[key]; exists && state.score <= old_val {
-					return true
-				}
 				// if len(state.moves) > flag_max_moves {
 				// 	return true
 				// }
-				sub_known_boards[key] = state.score
-				return false
+				return !sub_known_boards.Improve(state.TimedHash(requirements.MoveTimer, moves.Directions), state.score)
 			},
 			ScoreState: func(state AStarState) int {
 				// Ignore the currently held orb.
@@ -275,6 +281,8 @@ func StrategySolve(board Board, setup BoardSetup, requirements SolveRequirement)
 			},
 			// Determine allowable starting positions. If empty slice, search all.
 			StartingPositions: starting_positions,
+			MoveTimer: requirements.MoveTimer,
+			PriorDirections: moves.Directions,
 		}
 
 		// Create a board that ignores all values that aren't the given attribute.
@@ -285,7 +293,10 @@ func StrategySolve(board Board, setup BoardSetup, requirements SolveRequirement)
 			}
 		}
 
-		next_moves := AStarSolve(sub_board, sub_requirements)
+		result := AStarSolve(ctx, sub_board, sub_requirements)
+		next_moves := result.Moves
+		stats.Checked += result.Stats.Checked
+		stats.Skipped += result.Stats.Skipped
 		// Set up the first instantiation.
 		if len(moves.Directions) == 0 {
 			moves.Directions = next_moves.Directions
@@ -294,19 +305,22 @@ func StrategySolve(board Board, setup BoardSetup, requirements SolveRequirement)
 			moves.Directions = append(moves.Directions, next_moves.Directions...)
 		}
 
-		position := next_moves.StartingPosition
-		for _, direction := range next_moves.Directions {
-			new_board, err := current_board.Swap(position, direction)
-			if err != nil {
-				panic("Error occurred when expecting it not to.")
-			}
-			current_board = new_board
+		if result.Reason == TIMED_OUT || result.Reason == CANCELLED {
+			return SolveResult{moves, final_board(), stats, result.Reason}
+		}
+
+		// Replay the whole path so spinners spin for the time it has taken.
+		current_board = final_board()
+		position := moves.StartingPosition
+		for _, direction := range moves.Directions {
 			position = position.Swap(direction)
 		}
 		// Force the next starting position.
 		starting_positions = []Pair{position}
-		for _, pair := range combo.Positions {
-			current_board.Slots[pair.ToPos(current_board)].State |= TAPE
+		for _, done := range setup.Combos[:i + 1] {
+			for _, pair := range done.Positions {
+				current_board.Slots[pair.ToPos(current_board)].State |= TAPE
+			}
 		}
 	}
 
@@ -316,9 +330,30 @@ func StrategySolve(board Board, setup BoardSetup, requirements SolveRequirement)
 		RejectionFn: requirements.RejectionFn,
 		ScoreState: requirements.ScoreState,
 		StartingPositions: starting_positions,
+		Restriction: requirements.Restriction,
+		MoveTimer: requirements.MoveTimer,
+		PriorDirections: moves.Directions,
+	}
+	if requirements.OnImproved != nil {
+		setup_moves := moves
+		last_requirement.OnImproved = func(last_moves Moves, score int) {
+			if len(setup_moves.Directions) == 0 {
+				requirements.OnImproved(last_moves, score)
+				return
+			}
+			directions := append(append([]Direction{}, setup_moves.Directions...), last_moves.Directions...)
+			requirements.OnImproved(Moves{setup_moves.StartingPosition, directions}, score)
+		}
+	}
+	result := AStarSolve(ctx, current_board, last_requirement)
+	// Without a setup, the last solve picks the start.
+	if len(moves.Directions) == 0 {
+		moves.StartingPosition = result.Moves.StartingPosition
 	}
-	last_moves := AStarSolve(current_board, last_requirement)
-	moves.Directions = append(moves.Directions, last_moves.Directions...)
+	moves.Directions = append(moves.Directions, result.Moves.Directions...)
+	stats.Checked += result.Stats.Checked
+	stats.Skipped += result.Stats.Skipped
+	stats.Score = result.Stats.Score
 
-	return moves
+	return SolveResult{moves, final_board(), stats, result.Reason}
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Estimates how long a path takes to move, both for the TimeBudget and for
+// spinning spinners as the path is played. Continuing in a straight line is
+// faster than turning, and diagonals are slower than either. A zero timer
+// disables anything timing related, such as spinners.
+type MoveTimer struct {
+	// Seconds for a step in the same direction as the last step.
+	StraightStep float64
+	// Seconds for the first step and any step changing direction.
+	TurnStep float64
+	// Multiplier applied to diagonal steps.
+	DiagonalFactor float64
+}
+
+// Timer for a player moving straight at the given orbs per second, turning
+// takes half as long again. 10 steps a second is 0.1s straight and 0.15s
+// turning. A non-positive speed gives the zero timer.
+func MakeMoveTimer(steps_per_second float64) MoveTimer {
+	if steps_per_second <= 0 {
+		return MoveTimer{}
+	}
+	return MoveTimer{1 / steps_per_second, 1.5 / steps_per_second, 1.4}
+}
+
+func (self MoveTimer) Enabled() bool {
+	return self.StraightStep > 0 || self.TurnStep > 0
+}
+
+func IsDiagonal(direction Direction) bool {
+	switch direction {
+	case UP_RIGHT, DOWN_RIGHT, DOWN_LEFT, UP_LEFT:
+		return true
+	}
+	return false
+}
+
+// Seconds taken by step i of the path.
+func (self MoveTimer) StepSeconds(directions []Direction, i int) float64 {
+	step := self.TurnStep
+	if i > 0 && directions[i - 1] == directions[i] {
+		step = self.StraightStep
+	}
+	if IsDiagonal(directions[i]) {
+		step *= self.DiagonalFactor
+	}
+	return step
+}
+
+func (self MoveTimer) PathSeconds(directions []Direction) float64 {
+	total := 0.0
+	for i := range directions {
+		total += self.StepSeconds(directions, i)
+	}
+	return total
+}
+
+// Orb move time available to the player.
+type TimeBudget struct {
+	// Seconds before any extensions, usually 4 or 5.
+	Base float64
+	// Seconds added by leaders and awakenings, negative for hazards.
+	Extensions []float64
+}
+
+func (self TimeBudget) Seconds() float64 {
+	total := self.Base
+	for _, extension := range self.Extensions {
+		total += extension
+	}
+	return total
+}
+
+// Whether the budget limits anything. A non-positive Base is unlimited.
+func (self TimeBudget) Enabled() bool {
+	return self.Base > 0
+}
+
+func (self TimeBudget) Allows(timer MoveTimer, directions []Direction) bool {
+	return !self.Enabled() || timer.PathSeconds(directions) <= self.Seconds()
+}
+
+// Parses comma separated seconds, e.g. "1,0.5,-2".
+func ParseTimeExtensions(s string) ([]float64, error) {
+	extensions := make([]float64, 0)
+	if s == "" {
+		return extensions, nil
+	}
+	for _, value := range strings.Split(s, ",") {
+		extension, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return extensions, fmt.Errorf("Invalid time extension \"%s\"", value)
+		}
+		extensions = append(extensions, extension)
+	}
+	return extensions, nil
+}
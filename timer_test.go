@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMoveTimer_PathSeconds(t *testing.T) {
+	timer := MoveTimer{0.1, 0.2, 2}
+
+	tests := []struct {
+		name string
+		directions []Direction
+		expected float64
+	}{
+		{"Empty", []Direction{}, 0},
+		{"Straight", []Direction{RIGHT, RIGHT, RIGHT}, 0.4},
+		{"Turning", []Direction{RIGHT, DOWN, RIGHT}, 0.6},
+		{"Diagonal", []Direction{DOWN_RIGHT, DOWN_RIGHT}, 0.6},
+	}
+
+	for _, test := range tests {
+		seconds := timer.PathSeconds(test.directions)
+		if seconds < test.expected - 1e-9 || seconds > test.expected + 1e-9 {
+			t.Errorf("%s: expected %.2fs, got %.2fs", test.name, test.expected, seconds)
+		}
+	}
+}
+
+func TestMakeMoveTimer(t *testing.T) {
+	timer := MakeMoveTimer(10)
+	seconds := timer.PathSeconds([]Direction{RIGHT, RIGHT, DOWN})
+	if seconds < 0.4 - 1e-9 || seconds > 0.4 + 1e-9 {
+		t.Errorf("Expected 0.4s at 10 steps a second, got %.2fs", seconds)
+	}
+	if MakeMoveTimer(0).Enabled() || MakeMoveTimer(0).PathSeconds([]Direction{RIGHT}) != 0 {
+		t.Error("No speed should disable the timer.")
+	}
+}
+
+func TestTimeBudget_Allows(t *testing.T) {
+	extensions, err := ParseTimeExtensions("1, 0.5,-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := TimeBudget{1, extensions}
+	timer := MoveTimer{0.1, 0.1, 1}
+
+	if budget.Seconds() < 0.49 || budget.Seconds() > 0.51 {
+		t.Errorf("Expected a 0.5s budget, got %.2fs", budget.Seconds())
+	}
+	if !budget.Allows(timer, []Direction{LEFT, LEFT, LEFT, LEFT}) {
+		t.Error("0.4s should fit in the budget.")
+	}
+	if budget.Allows(timer, []Direction{LEFT, LEFT, LEFT, LEFT, LEFT, LEFT}) {
+		t.Error("0.6s should not fit in the budget.")
+	}
+	if !(TimeBudget{}).Allows(timer, make([]Direction, 1000)) {
+		t.Error("An empty budget should not limit paths.")
+	}
+	if _, err := ParseTimeExtensions("1,two"); err == nil {
+		t.Error("Invalid extensions should error.")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"sync"
+)
+
+// Number of locks in a TranspositionTable, each guarding every entry whose
+// index has the same low bits, so workers rarely wait on each other.
+const TRANSPOSITION_LOCKS = 64
+
+// Smallest table, in bits of the entry index. Hashes which share an entry
+// always share their low TRANSPOSITION_MIN_BITS bits, see parallelOwner.
+const TRANSPOSITION_MIN_BITS = 16
+
+// Size of the table used by MakeRejectionFunction, 16 bytes per entry.
+const TRANSPOSITION_BITS = 18
+
+// Size of the cache used by expected value score functions, 24 bytes per
+// entry.
+const SCORE_CACHE_BITS = 16
+
+type transpositionEntry struct {
+	hash uint64
+	score int32
+	filled bool
+}
+
+// Best score seen for each search position by Zobrist hash, safe for
+// concurrent use. The table has a fixed number of entries and a new hash
+// replaces whatever shares its entry, so memory never grows but positions
+// may be searched again once they are replaced.
+type TranspositionTable struct {
+	entries []transpositionEntry
+	locks []sync.Mutex
+	mask uint64
+}
+
+// Table with 1 << bits entries, and at least 1 << TRANSPOSITION_MIN_BITS.
+func MakeTranspositionTable(bits uint) *TranspositionTable {
+	if bits < TRANSPOSITION_MIN_BITS {
+		bits = TRANSPOSITION_MIN_BITS
+	}
+	return &TranspositionTable{
+		entries: make([]transpositionEntry, 1 << bits),
+		locks: make([]sync.Mutex, TRANSPOSITION_LOCKS),
+		mask: 1 << bits - 1,
+	}
+}
+
+// Records the score for the hash, returning false if the hash already has a
+// score at least as high.
+func (self *TranspositionTable) Improve(hash uint64, score int) bool {
+	index := hash & self.mask
+	lock := &self.locks[index % TRANSPOSITION_LOCKS]
+	lock.Lock()
+	defer lock.Unlock()
+	entry := &self.entries[index]
+	if entry.filled && entry.hash == hash && int32(score) <= entry.score {
+		return false
+	}
+	*entry = transpositionEntry{hash, int32(score), true}
+	return true
+}
+
+// Number of filled entries in the table.
+func (self *TranspositionTable) Len() int {
+	for i := range self.locks {
+		self.locks[i].Lock()
+		defer self.locks[i].Unlock()
+	}
+	total := 0
+	for _, entry := range self.entries {
+		if entry.filled {
+			total++
+		}
+	}
+	return total
+}
+
+type scoreCacheEntry struct {
+	hash uint64
+	value float64
+	filled bool
+}
+
+// Values already worked out for boards by Zobrist hash. Like
+// TranspositionTable, a new hash replaces whatever shares its entry so memory
+// never grows, but unlike it the cache is not safe for concurrent use.
+type ScoreCache struct {
+	entries []scoreCacheEntry
+	mask uint64
+}
+
+func MakeScoreCache(bits uint) *ScoreCache {
+	return &ScoreCache{make([]scoreCacheEntry, 1 << bits), 1 << bits - 1}
+}
+
+func (self *ScoreCache) Get(hash uint64) (float64, bool) {
+	entry := self.entries[hash & self.mask]
+	return entry.value, entry.filled && entry.hash == hash
+}
+
+func (self *ScoreCache) Set(hash uint64, value float64) {
+	self.entries[hash & self.mask] = scoreCacheEntry{hash, value, true}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTranspositionTable_Improve(t *testing.T) {
+	table := MakeTranspositionTable(TRANSPOSITION_MIN_BITS)
+	if !table.Improve(42, 5) {
+		t.Error("Expected a new hash to be recorded.")
+	}
+	if table.Improve(42, 5) || table.Improve(42, 3) {
+		t.Error("Expected scores no higher than the old one to be rejected.")
+	}
+	if !table.Improve(42, 6) {
+		t.Error("Expected a higher score to be recorded.")
+	}
+	if table.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", table.Len())
+	}
+
+	// Shares the entry of 42, so replaces it.
+	if !table.Improve(42 + 1 << TRANSPOSITION_MIN_BITS, 1) || !table.Improve(42, 1) {
+		t.Error("Expected hashes sharing an entry to replace each other.")
+	}
+	if table.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", table.Len())
+	}
+}
+
+func TestTranspositionTable_Concurrent(t *testing.T) {
+	table := MakeTranspositionTable(TRANSPOSITION_MIN_BITS)
+	improved := make([]int, 8)
+	wait_group := sync.WaitGroup{}
+	for worker := range improved {
+		wait_group.Add(1)
+		go func(worker int) {
+			defer wait_group.Done()
+			for i := uint64(0); i < 1000; i++ {
+				if table.Improve(i, 1) {
+					improved[worker]++
+				}
+			}
+		}(worker)
+	}
+	wait_group.Wait()
+
+	total := 0
+	for _, count := range improved {
+		total += count
+	}
+	if total != 1000 || table.Len() != 1000 {
+		t.Errorf("Expected each hash to be recorded once, got %d records of %d entries", total, table.Len())
+	}
+}
+
+func TestScoreCache_ReplacesSharedEntries(t *testing.T) {
+	cache := MakeScoreCache(4)
+	cache.Set(3, 1.5)
+	if value, exists := cache.Get(3); !exists || value != 1.5 {
+		t.Errorf("Expected 1.5, got %f", value)
+	}
+	cache.Set(3 + 1 << 4, 2.5)
+	if _, exists := cache.Get(3); exists {
+		t.Error("Expected hashes sharing an entry to replace each other.")
+	}
+	if len(cache.entries) != 16 {
+		t.Errorf("Expected the cache to stay at 16 entries, got %d", len(cache.entries))
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const SESSION_HELP = `Commands:
+  n, next, <enter>    Step forward.
+  p, prev             Step back.
+  first, last         Jump to the start or end.
+  set <y> <x> <orb>   Replace a space, e.g. "set 0 2 &R+". See markup.go.
+  undo, redo          Undo or redo an edit or solve.
+  solve               Solve the current board again.
+  help                Show this message.
+  q, quit             Exit.`
+
+// A single point while stepping through a solution.
+type StepFrame struct {
+	Board Board
+	Description string
+	// Position of the orb in the player's hand, if any.
+	Held Pair
+	IsHeld bool
+	// Combos about to be cleared, if any.
+	Combos []BoardCombo
+}
+
+// The board and moves before an edit.
+type sessionSnapshot struct {
+	board Board
+	moves Moves
+}
+
+// Steps through a solution one move and one cascade at a time, and allows the
+// board to be edited and solved again.
+type Session struct {
+	Board Board
+	Moves Moves
+	// Spins spinners as the moves are stepped through, the same as when solving.
+	Timer MoveTimer
+	// Solves the board again after edits.
+	Solve func(Board) Moves
+	Renderer ANSIRenderer
+
+	frames []StepFrame
+	frame int
+	undo []sessionSnapshot
+	redo []sessionSnapshot
+}
+
+func MakeSession(board Board, moves Moves, timer MoveTimer, solve func(Board) Moves, renderer ANSIRenderer) *Session {
+	session := &Session{Board: board, Moves: moves, Timer: timer, Solve: solve, Renderer: renderer}
+	session.buildFrames()
+	return session
+}
+
+// Recreates every frame from the board and moves, starting from the first.
+func (self *Session) buildFrames() {
+	self.frame = 0
+	placement := self.Moves.StartingPosition
+	current_board := self.Board.Clone()
+	on_board := placement.Y < current_board.Height && placement.X < current_board.Width
+	self.frames = []StepFrame{StepFrame{
+		Board: current_board,
+		Description: fmt.Sprintf("Start: %d moves from %s", len(self.Moves.Directions), placement),
+		Held: placement,
+		IsHeld: len(self.Moves.Directions) > 0 && on_board,
+	}}
+	elapsed := 0.0
+	for i, direction := range self.Moves.Directions {
+		new_board, err := current_board.Swap(placement, direction)
+		if err != nil {
+			self.frames = append(self.frames, StepFrame{
+				Board: current_board,
+				Description: fmt.Sprintf("Move %d/%d: %s is invalid: %s", i + 1, len(self.Moves.Directions), direction, err),
+			})
+			return
+		}
+		current_board = new_board
+		placement = placement.Swap(direction)
+		if self.Timer.Enabled() {
+			step := self.Timer.StepSeconds(self.Moves.Directions, i)
+			current_board.TickSpinners(elapsed, elapsed + step, placement)
+			elapsed += step
+		}
+		held := current_board.GetOrbAt(placement).Attribute
+		self.frames = append(self.frames, StepFrame{
+			Board: current_board,
+			Description: fmt.Sprintf("Move %d/%d: %s, holding %s at %s",
+				i + 1, len(self.Moves.Directions), direction, held, placement),
+			Held: placement,
+			IsHeld: true,
+		})
+	}
+
+	steps := current_board.GetCascadeSteps()
+	total := 0
+	for i, step := range steps {
+		total += len(step.Combos)
+		self.frames = append(self.frames, StepFrame{
+			Board: step.Board,
+			Description: fmt.Sprintf("Cascade %d/%d: %d combos", i + 1, len(steps), len(step.Combos)),
+			Combos: step.Combos,
+		})
+	}
+	final_board := current_board
+	if len(steps) > 0 {
+		final_board = steps[len(steps) - 1].Board.Clone()
+		for _, combo := range steps[len(steps) - 1].Combos {
+			for _, combo_placement := range combo.Positions {
+				final_board.Slots[combo_placement.ToPos(final_board)].Orb.Attribute = EMPTY
+			}
+		}
+		final_board.dropOrbs()
+	}
+	self.frames = append(self.frames, StepFrame{
+		Board: final_board,
+		Description: fmt.Sprintf("Finished: %d combos", total),
+	})
+}
+
+func (self *Session) Frame() StepFrame {
+	return self.frames[self.frame]
+}
+
+// Draws the current frame. The held orb is wrapped in > <, and orbs about to
+// be cleared in * *.
+func (self *Session) Render() string {
+	frame := self.Frame()
+	text := make([]string, len(frame.Board.Slots))
+	highlighted := make([]bool, len(frame.Board.Slots))
+	for i, slot := range frame.Board.Slots {
+		text[i] = slot.String()
+		highlighted[i] = len(frame.Combos) == 0
+	}
+	for _, combo := range frame.Combos {
+		for _, placement := range combo.Positions {
+			pos := placement.ToPos(frame.Board)
+			text[pos] = "*" + AttributeToLetter[combo.Attribute] + "*"
+			highlighted[pos] = true
+		}
+	}
+	if frame.IsHeld {
+		pos := frame.Held.ToPos(frame.Board)
+		text[pos] = ">" + AttributeToLetter[frame.Board.Slots[pos].Orb.Attribute] + "<"
+	}
+	lines := self.Renderer.boardLines(frame.Board, text, highlighted)
+	return fmt.Sprintf("[%d/%d] %s\n%s\n", self.frame + 1, len(self.frames), frame.Description,
+		strings.Join(lines, "\n"))
+}
+
+func (self *Session) snapshot() sessionSnapshot {
+	return sessionSnapshot{self.Board.Clone(), self.Moves}
+}
+
+func (self *Session) restore(snapshot sessionSnapshot) {
+	self.Board = snapshot.board
+	self.Moves = snapshot.moves
+	self.buildFrames()
+}
+
+func (self *Session) Set(placement Pair, space BoardSpace) error {
+	if placement.Y >= self.Board.Height || placement.X >= self.Board.Width {
+		return fmt.Errorf("%s is off the board", placement)
+	}
+	self.undo = append(self.undo, self.snapshot())
+	self.redo = nil
+	self.Board = self.Board.Clone()
+	self.Board.Slots[placement.ToPos(self.Board)] = space
+	self.buildFrames()
+	return nil
+}
+
+func (self *Session) Undo() error {
+	if len(self.undo) == 0 {
+		return fmt.Errorf("Nothing to undo")
+	}
+	self.redo = append(self.redo, self.snapshot())
+	self.restore(self.undo[len(self.undo) - 1])
+	self.undo = self.undo[:len(self.undo) - 1]
+	return nil
+}
+
+func (self *Session) Redo() error {
+	if len(self.redo) == 0 {
+		return fmt.Errorf("Nothing to redo")
+	}
+	self.undo = append(self.undo, self.snapshot())
+	self.restore(self.redo[len(self.redo) - 1])
+	self.redo = self.redo[:len(self.redo) - 1]
+	return nil
+}
+
+// Runs a single command, returning whether the session should end.
+func (self *Session) Execute(command string) (bool, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		fields = []string{"next"}
+	}
+	switch fields[0] {
+	case "n", "next":
+		if self.frame < len(self.frames) - 1 {
+			self.frame++
+		}
+	case "p", "prev":
+		if self.frame > 0 {
+			self.frame--
+		}
+	case "first":
+		self.frame = 0
+	case "last":
+		self.frame = len(self.frames) - 1
+	case "set":
+		if len(fields) != 4 {
+			return false, fmt.Errorf("Usage: set <y> <x> <orb>")
+		}
+		// Checked before narrowing to uint8, which would wrap e.g. 260 to 4.
+		y, err := strconv.Atoi(fields[1])
+		if err != nil || y < 0 || y >= int(self.Board.Height) {
+			return false, fmt.Errorf("Invalid row \"%s\"", fields[1])
+		}
+		x, err := strconv.Atoi(fields[2])
+		if err != nil || x < 0 || x >= int(self.Board.Width) {
+			return false, fmt.Errorf("Invalid column \"%s\"", fields[2])
+		}
+		space, err := ParseSpace(fields[3])
+		if err != nil {
+			return false, err
+		}
+		return false, self.Set(Pair{uint8(y), uint8(x)}, space)
+	case "undo":
+		return false, self.Undo()
+	case "redo":
+		return false, self.Redo()
+	case "solve":
+		if self.Solve == nil {
+			return false, fmt.Errorf("Solving is not available")
+		}
+		self.undo = append(self.undo, self.snapshot())
+		self.redo = nil
+		self.Moves = self.Solve(self.Board)
+		self.buildFrames()
+	case "q", "quit":
+		return true, nil
+	case "help":
+	default:
+		return false, fmt.Errorf("Unknown command \"%s\"", fields[0])
+	}
+	return false, nil
+}
+
+// Reads commands line by line until quit or the end of input, rendering the
+// current frame after each one.
+func (self *Session) Run(reader io.Reader, writer io.Writer) error {
+	fmt.Fprintln(writer, SESSION_HELP)
+	fmt.Fprint(writer, self.Render())
+	scanner := bufio.NewScanner(reader)
+	for fmt.Fprint(writer, "> "); scanner.Scan(); fmt.Fprint(writer, "> ") {
+		command := strings.TrimSpace(scanner.Text())
+		quit, err := self.Execute(command)
+		if quit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(writer, err)
+			continue
+		}
+		if command == "help" {
+			fmt.Fprintln(writer, SESSION_HELP)
+		}
+		fmt.Fprint(writer, self.Render())
+	}
+	return scanner.Err()
+}
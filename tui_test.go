@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSession_StepsThroughMovesAndCascades(t *testing.T) {
+	moves := Moves{Pair{4, 5}, []Direction{UP, LEFT}}
+	session := MakeSession(swng_board, moves, MoveTimer{}, nil, ANSIRenderer{false})
+	final_board, _ := swng_board.ApplyMoves(moves, MoveTimer{})
+	cascades := len(final_board.GetCascadeSteps())
+
+	// Start, a frame per move, a frame per cascade and the finished board.
+	if len(session.frames) != 1 + len(moves.Directions) + cascades + 1 {
+		t.Fatalf("Unexpected frame count %d", len(session.frames))
+	}
+	session.Execute("next")
+	if !session.Frame().IsHeld || session.Frame().Held != (Pair{3, 5}) {
+		t.Errorf("Expected to hold the orb at (3,5): %s", session.Render())
+	}
+	session.Execute("last")
+	session.Execute("n")
+	if session.frame != len(session.frames) - 1 {
+		t.Error("Stepping past the end should stay on the last frame.")
+	}
+	session.Execute("p")
+	if len(session.Frame().Combos) == 0 || !strings.Contains(session.Render(), "*") {
+		t.Errorf("Expected a cascade frame: %s", session.Render())
+	}
+	session.Execute("first")
+	session.Execute("prev")
+	if session.frame != 0 {
+		t.Error("Stepping before the start should stay on the first frame.")
+	}
+}
+
+func TestSession_SpinsSpinners(t *testing.T) {
+	board, _ := ParseBoard("LL[G]RBHDHB", 3)
+	moves := Moves{Pair{2, 0}, []Direction{RIGHT, RIGHT, UP}}
+	timer := MoveTimer{0.25, 0.5, 1}
+	session := MakeSession(board, moves, timer, nil, ANSIRenderer{false})
+	moved, _ := board.ApplyMoves(moves, timer)
+
+	released := session.frames[len(moves.Directions)].Board
+	if released.SimpleString() != moved.SimpleString() {
+		t.Errorf("Expected the last move to match ApplyMoves:\n%s\n%s", released, moved)
+	}
+	if session.frames[len(session.frames) - 1].Description != "Finished: 1 combos" {
+		t.Errorf("Expected the spun Light combo: %s", session.frames[len(session.frames) - 1].Description)
+	}
+}
+
+func TestSession_EditUndoRedo(t *testing.T) {
+	session := MakeSession(swng_board, Moves{Pair{0, 0}, []Direction{}}, MoveTimer{}, nil, ANSIRenderer{false})
+
+	if _, err := session.Execute("set 0 0 &L+"); err != nil {
+		t.Fatal(err)
+	}
+	if session.Board.Slots[0].Orb != (Orb{LIGHT, LOCKED | ENHANCED}) || swng_board.Slots[0].Orb.Attribute != FIRE {
+		t.Errorf("Only the session board should be edited: %v", session.Board.Slots[0])
+	}
+	session.Execute("undo")
+	if session.Board.Slots[0].Orb.Attribute != FIRE {
+		t.Error("Undo should restore fire.")
+	}
+	session.Execute("redo")
+	if session.Board.Slots[0].Orb.Attribute != LIGHT {
+		t.Error("Redo should restore light.")
+	}
+	if _, err := session.Execute("redo"); err == nil {
+		t.Error("Nothing should be left to redo.")
+	}
+	for _, command := range []string{"set 9 0 R", "set 0 0 X", "set 0 R", "dance"} {
+		if _, err := session.Execute(command); err == nil {
+			t.Errorf("Expected an error for \"%s\"", command)
+		}
+	}
+}
+
+func TestSession_SetRejectsOutOfRange(t *testing.T) {
+	session := MakeSession(swng_board, Moves{Pair{0, 0}, []Direction{}}, MoveTimer{}, nil, ANSIRenderer{false})
+	before := session.Board.SimpleString()
+
+	// 260 and 261 would wrap to row 4 and column 5 as uint8.
+	for _, command := range []string{"set 260 0 R", "set 0 261 R", "set 5 0 R", "set 0 6 R", "set -1 0 R"} {
+		if _, err := session.Execute(command); err == nil {
+			t.Errorf("Expected an error for \"%s\"", command)
+		}
+	}
+	if session.Board.SimpleString() != before || len(session.undo) != 0 {
+		t.Errorf("Out of range edits should not change the board:\n%s", session.Board)
+	}
+}
+
+func TestSession_SolveAndRun(t *testing.T) {
+	solved := Moves{Pair{4, 5}, []Direction{UP}}
+	session := MakeSession(swng_board, Moves{Pair{0, 0}, []Direction{}}, MoveTimer{}, func(board Board) Moves {
+		return solved
+	}, ANSIRenderer{false})
+
+	output := bytes.Buffer{}
+	if err := session.Run(strings.NewReader("solve\nnext\nq\nnext\n"), &output); err != nil {
+		t.Fatal(err)
+	}
+	if session.Moves.String() != solved.String() || session.frame != 1 {
+		t.Errorf("Expected to stop on the first move of the new solution:\n%s", output.String())
+	}
+}
+
+func TestSession_SolveCanBeUndone(t *testing.T) {
+	original := Moves{Pair{0, 0}, []Direction{RIGHT}}
+	solved := Moves{Pair{4, 5}, []Direction{UP}}
+	session := MakeSession(swng_board, original, MoveTimer{}, func(board Board) Moves {
+		return solved
+	}, ANSIRenderer{false})
+
+	session.Execute("set 0 0 L")
+	session.Execute("undo")
+	if _, err := session.Execute("solve"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.Execute("redo"); err == nil {
+		t.Error("Solving should clear what was left to redo.")
+	}
+	if _, err := session.Execute("undo"); err != nil || session.Moves.String() != original.String() {
+		t.Errorf("Expected undo to restore %s, got %s", original, session.Moves)
+	}
+	if _, err := session.Execute("redo"); err != nil || session.Moves.String() != solved.String() {
+		t.Errorf("Expected redo to restore %s, got %s", solved, session.Moves)
+	}
+}
+
+func TestSession_RendersStartOffTheBoard(t *testing.T) {
+	for _, start := range []Pair{Pair{swng_board.Height, 0}, Pair{0, swng_board.Width}, Pair{255, 255}} {
+		session := MakeSession(swng_board, Moves{start, []Direction{LEFT}}, MoveTimer{}, nil, ANSIRenderer{false})
+		if session.Frame().IsHeld {
+			t.Errorf("%s: nothing should be held off the board", start)
+		}
+		// Every frame, including the invalid move, should render.
+		for i := range session.frames {
+			session.frame = i
+			session.Render()
+		}
+	}
+}
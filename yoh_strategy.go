@@ -37,6 +37,13 @@ func YohAnalyze(board Board) YohAnalysis {
 	fallback_extra := make([]OrbAttribute, 0)
 	three_matches := make([]OrbAttribute, 0)
 	for attribute, count := range orb_to_count {
+		// Attributes which need more than 3 orbs can't fill the threes.
+		if board.MinimumMatchFor(attribute) > 3 {
+			if attribute != WOOD && count >= 5 && board.MinimumMatchFor(attribute) <= 5 {
+				fallback_five_match = append(fallback_five_match, attribute)
+			}
+			continue
+		}
 		if count % 3 == 1 {
 			priority_extra = append(priority_extra, attribute)
 		} else if count % 3 == 2 {
@@ -88,7 +95,7 @@ func YohFindSetup(board Board) BoardSetup {
 	potential_board_setups := make([]BoardSetup, 0)
 
 	// This is for wood row strategies, impossible to do.
-	if analysis.wood_count < 6 {
+	if analysis.wood_count < 6 || board.MinimumMatchFor(WOOD) > 6 {
 		return BoardSetup{}
 	}
 	five_match_attrs := analysis.priority_five_match
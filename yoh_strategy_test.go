@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
@@ -34,7 +35,7 @@ func TestYohRowSolve_RandomBoard(t *testing.T) {
 	board := CreateBoard("GHDBDLDGBLGGHLHLRGLDRHLRGLRLBB", 6)
 	setup := YohFindSetup(board)
 
-	moves := StrategySolve(board, setup, SolveRequirement{
+	moves := StrategySolve(context.Background(), board, setup, SolveRequirement{
 		AllowDiagonals: false,
 		FinishedFn: func(state AStarState) bool {
 			return len(state.board.GetAllCombos()) >= 7
@@ -42,8 +43,8 @@ func TestYohRowSolve_RandomBoard(t *testing.T) {
 		ScoreState: func(state AStarState) int {
 			return len(state.board.GetAllCombos()) * 17 - len(state.moves)
 		},
-		RejectionFn: MakeRejectionFunction(),
-	})
+		RejectionFn: MakeRejectionFunction(MakeSolveSettings()),
+	}).Moves
 	fmt.Println(moves)
 	fmt.Println(len(moves.Directions))
 	fmt.Println(ToDawnglare(board, moves))
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Zobrist hashes XOR together a random key for what is at each position, so
+// a swap only has to XOR out the two old orbs and XOR in the two new ones.
+// Boards with more slots than this reuse the keys of earlier positions.
+const ZOBRIST_POSITIONS = 64
+
+// Keys for each attribute and each combination of orb states, by position.
+// Space states are left out since swaps never move them.
+var zobristAttributes, zobristOrbStates, zobristCursors = makeZobristKeys()
+
+func makeZobristKeys() ([ZOBRIST_POSITIONS][16]uint64, [ZOBRIST_POSITIONS][64]uint64, [ZOBRIST_POSITIONS]uint64) {
+	// Seeded so hashes are the same on every run.
+	random := rand.New(rand.NewSource(0x5eed))
+	var attributes [ZOBRIST_POSITIONS][16]uint64
+	var orb_states [ZOBRIST_POSITIONS][64]uint64
+	var cursors [ZOBRIST_POSITIONS]uint64
+	for pos := 0; pos < ZOBRIST_POSITIONS; pos++ {
+		for i := range attributes[pos] {
+			attributes[pos][i] = random.Uint64()
+		}
+		// Orbs without a state don't change the hash.
+		for i := 1; i < len(orb_states[pos]); i++ {
+			orb_states[pos][i] = random.Uint64()
+		}
+		cursors[pos] = random.Uint64()
+	}
+	return attributes, orb_states, cursors
+}
+
+func zobristOrb(pos int, orb Orb) uint64 {
+	pos %= ZOBRIST_POSITIONS
+	return zobristAttributes[pos][orb.Attribute & 15] ^ zobristOrbStates[pos][orb.State & 63]
+}
+
+// Zobrist hash of the orbs and their states. Uses the hash kept by Swap and
+// TickSpinners since the last Rehash, if there is one.
+func (self Board) Hash() uint64 {
+	if self.hashed {
+		return self.hash
+	}
+	hash := uint64(0)
+	for i, slot := range self.Slots {
+		hash ^= zobristOrb(i, slot.Orb)
+	}
+	return hash
+}
+
+// Starts keeping the hash up to date as orbs are swapped and spun. Boards from
+// Clone or changed through Slots directly need to be rehashed.
+func (self *Board) Rehash() {
+	self.hashed = false
+	self.hash = self.Hash()
+	self.hashed = true
+}
+
+// Hash of the board and the position of the held orb.
+func (self AStarState) Hash() uint64 {
+	return self.board.Hash() ^ zobristCursors[int(self.current_pos.ToPos(self.board)) % ZOBRIST_POSITIONS]
+}
+
+// Mixes the bits as in splitmix64, so nearby values give unrelated keys.
+func mixBits(value uint64) uint64 {
+	value = (value ^ value >> 30) * 0xbf58476d1ce4e5b9
+	value = (value ^ value >> 27) * 0x94d049bb133111eb
+	return value ^ value >> 31
+}
+
+// Hash of the state for transposition tables. With the timer enabled, the
+// same board reached at another time spins its spinners differently, and the
+// next step is only faster when it continues the last direction, so the time
+// taken and the last direction are part of the hash too. Prior_directions are
+// the moves made before this search, as in SolveRequirement.
+func (self AStarState) TimedHash(timer MoveTimer, prior_directions []Direction) uint64 {
+	hash := self.Hash()
+	if !timer.Enabled() || len(self.moves) == 0 {
+		return hash
+	}
+	path := self.moves
+	if len(prior_directions) > 0 {
+		path = append(append([]Direction{}, prior_directions...), self.moves...)
+	}
+	// Whole milliseconds, so sums of the same steps in another order agree.
+	elapsed := uint64(math.Round(timer.PathSeconds(path) * 1000))
+	return hash ^ mixBits(elapsed << 4 | uint64(path[len(path) - 1]))
+}
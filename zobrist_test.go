@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBoardHash_KeptBySwapAndSpinners(t *testing.T) {
+	// R  ?B [G]
+	// L+  D (H)
+	board, err := ParseBoard("R?B[G]L+D(H)", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.Rehash()
+	placement := Pair{1, 0}
+	for i, direction := range []Direction{RIGHT, UP, RIGHT, DOWN, LEFT, UP_LEFT} {
+		board, err = board.Swap(placement, direction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		placement = placement.Swap(direction)
+		board.TickSpinners(float64(i), float64(i + 1), placement)
+		if board.Hash() != board.Clone().Hash() {
+			t.Fatalf("Expected the kept hash to match the board after %d moves:\n%s", i + 1, board)
+		}
+	}
+	if !board.hashed {
+		t.Error("Expected the hash to still be kept")
+	}
+}
+
+func TestBoardHash_DistinguishesBoards(t *testing.T) {
+	board := CreateBoard("RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", 6)
+	enhanced := CreateBoard("R+RHHHDGDGGGDGDDRRRRDRGGDGRRDDG", 6)
+	swapped, _ := board.Swap(Pair{0, 1}, RIGHT)
+	if board.Hash() == enhanced.Hash() || board.Hash() == swapped.Hash() {
+		t.Error("Expected different orbs to change the hash")
+	}
+
+	state := AStarState{board: board, current_pos: Pair{0, 0}}
+	moved := AStarState{board: board, current_pos: Pair{0, 1}}
+	if state.Hash() == moved.Hash() {
+		t.Error("Expected the held orb's position to change the hash")
+	}
+}
+
+func TestAStarStateTimedHash_KeepsTime(t *testing.T) {
+	board := CreateBoard("RRHHHDGDGGGDGDDRRRRDRGGDGRRDDG", 6)
+	timer := MakeMoveTimer(10)
+	// Both end on the same board and position, but turning every step takes
+	// longer than two straight lines.
+	turning := AStarState{board: board, current_pos: Pair{2, 2}, moves: []Direction{RIGHT, DOWN, RIGHT, DOWN}}
+	straight := AStarState{board: board, current_pos: Pair{2, 2}, moves: []Direction{RIGHT, RIGHT, DOWN, DOWN}}
+
+	if turning.TimedHash(MoveTimer{}, nil) != turning.Hash() {
+		t.Error("Expected the zero timer to leave the hash alone")
+	}
+	if turning.TimedHash(timer, nil) == straight.TimedHash(timer, nil) {
+		t.Error("Expected the time taken to change the hash")
+	}
+	if turning.TimedHash(timer, nil) != turning.Clone().TimedHash(timer, nil) {
+		t.Error("Expected the same path to give the same hash")
+	}
+	if turning.TimedHash(timer, []Direction{DOWN}) == turning.TimedHash(timer, []Direction{RIGHT}) {
+		t.Error("Expected prior directions to change the time taken")
+	}
+	left := AStarState{board: board, current_pos: Pair{2, 2}, moves: []Direction{UP, LEFT}}
+	up := AStarState{board: board, current_pos: Pair{2, 2}, moves: []Direction{LEFT, UP}}
+	if left.TimedHash(timer, nil) == up.TimedHash(timer, nil) {
+		t.Error("Expected the last direction to change the hash")
+	}
+}